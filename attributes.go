@@ -0,0 +1,130 @@
+package tplinator
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// attributeBindingPrefixes are the prefixes of the attributes whose value
+// is an expression that computes the whole value of the attribute that is
+// named after the prefix, e.g. `go-attr-href="link.url"` or `:href="link.url"`.
+var attributeBindingPrefixes = []string{"go-attr-", ":"}
+
+// booleanAttributeNames are the attributes that can be toggled using a
+// `go-*` directive with a boolean expression, e.g. `go-checked="isChecked"`.
+var booleanAttributeNames = []string{
+	"checked", "selected", "disabled", "readonly", "required", "hidden", "open",
+}
+
+type attributeBinding struct {
+	key        string
+	expression string
+	// isBoolean is set if the expression must be a condition, in which
+	// case a missing variable removes the attribute.
+	isBoolean bool
+}
+
+// AttributeBindingExtension sets the attributes of an element to the
+// values of their expressions. An attribute is removed if its value is nil
+// or false and is rendered without a value if its value is true.
+type AttributeBindingExtension struct {
+	bindings []attributeBinding
+}
+
+func (abe *AttributeBindingExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
+	copyNode := CopyNode(node)
+	for _, binding := range abe.bindings {
+		var value interface{}
+		var err error
+		if binding.isBoolean {
+			value, err = evaluateBoolPipeline(node, dependencies, binding.expression, params)
+		} else {
+			value, err = evaluatePipeline(node, dependencies, binding.expression, params)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("attr binding ext: %w", err)
+		}
+		if err := bindAttribute(copyNode, dependencies, binding.key, value); err != nil {
+			return nil, nil, fmt.Errorf("attr binding ext: `%v`: %v", binding.expression, err)
+		}
+	}
+	return copyNode, nil, nil
+}
+
+func (abe *AttributeBindingExtension) Expressions() []string {
+	expressions := make([]string, len(abe.bindings))
+	for bindingIdx, binding := range abe.bindings {
+		expressions[bindingIdx] = binding.expression
+	}
+	return expressions
+}
+
+func bindAttribute(node *Node, dependencies ExtensionDependencies, key string, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		node.RemoveAttribute(key)
+	case bool:
+		if value {
+			setKeyOnlyAttribute(node, key)
+		} else {
+			node.RemoveAttribute(key)
+		}
+	case missingValue:
+		if value.policy != MissingKeyPlaceholder {
+			node.RemoveAttribute(key)
+			return nil
+		}
+		node.AddAttribute(key, html.EscapeString(value.String()))
+	default:
+		formattedValue, err := formatValue(dependencies, value)
+		if err != nil {
+			return err
+		}
+		node.AddAttribute(key, html.EscapeString(formattedValue))
+	}
+	return nil
+}
+
+func setKeyOnlyAttribute(node *Node, key string) {
+	node.AddAttribute(key, "")
+	_, attrIdx, _ := node.HasAttribute(key)
+	node.attributes[attrIdx].KeyOnly = true
+}
+
+// AttributeBindingExtensionNodeProcessor adds the AttributeBindingExtension
+// to the elements that have `go-attr-*`, `:*` or boolean attribute
+// directives.
+func AttributeBindingExtensionNodeProcessor(node *Node) {
+	var bindings []attributeBinding
+	for _, key := range booleanAttributeNames {
+		if hasDirective, _, expression := node.HasAttribute("go-" + key); hasDirective {
+			bindings = append(bindings, attributeBinding{
+				key:        key,
+				expression: strings.TrimSpace(expression),
+				isBoolean:  true,
+			})
+			node.RemoveAttribute("go-" + key)
+		}
+	}
+	for _, attr := range node.Attributes() {
+		for _, prefix := range attributeBindingPrefixes {
+			if !strings.HasPrefix(attr.Key, prefix) {
+				continue
+			}
+			key := strings.TrimSpace(strings.TrimPrefix(attr.Key, prefix))
+			if key != "" {
+				bindings = append(bindings, attributeBinding{
+					key:        key,
+					expression: strings.TrimSpace(attr.Value),
+				})
+				node.RemoveAttribute(attr.Key)
+			}
+			break
+		}
+	}
+	if len(bindings) > 0 {
+		node.AddExtension(&AttributeBindingExtension{bindings: bindings})
+	}
+}
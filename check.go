@@ -0,0 +1,547 @@
+package tplinator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type TypeCheckError struct {
+	Position   Position
+	Expression string
+	Message    string
+}
+
+func (e *TypeCheckError) Error() string {
+	msg := "`" + e.Expression + "`: " + e.Message
+	if e.Position.IsValid() {
+		msg = e.Position.String() + ": " + msg
+	}
+	return msg
+}
+
+type TypeCheckErrors []*TypeCheckError
+
+func (errs TypeCheckErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for errIdx, err := range errs {
+		msgs[errIdx] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Check verifies the expressions of the template against the type of the
+// params that will be used to render it, e.g. a struct that is rendered
+// using Template#RenderValue. It returns TypeCheckErrors that has every
+// variable or field that does not exist, every condition that is not a
+// boolean and every range source that cannot be ranged over.
+//
+// The parts of an expression that have an interface type (e.g. the values
+// of an EvaluatorParams) cannot be checked and are assumed to be valid.
+func Check(tpl *Template, paramsType reflect.Type) error {
+	if err := checkParamsType(paramsType); err != nil {
+		return fmt.Errorf("check: %v", err)
+	}
+
+	funcs := mergeFuncMaps(tpl.extDeps.getFuncs(), localeFuncs(nil, &tpl.extDeps, nil, nil), builtinFuncs)
+
+	checker := &typeChecker{
+		funcs:   funcs,
+		filters: tpl.extDeps.getFilters(),
+		scopes:  []checkScope{{params: paramsType, isMerged: true}},
+	}
+	for _, rootNode := range tpl.rootNodes {
+		if err := walkExpressions(rootNode, checker); err != nil {
+			return err
+		}
+	}
+	if len(checker.errs) > 0 {
+		return checker.errs
+	}
+	return nil
+}
+
+func checkParamsType(t reflect.Type) error {
+	if t == nil {
+		return fmt.Errorf("cannot use a nil type as params")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Struct, t.Kind() == reflect.Interface:
+		return nil
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		return nil
+	default:
+		return fmt.Errorf("cannot use %v as params", t)
+	}
+}
+
+// typePreservingFilters are the built-in filters whose result has the
+// same type as their input.
+var typePreservingFilters = map[string]bool{
+	"sortBy": true,
+	"limit":  true,
+}
+
+type typeChecker struct {
+	funcs   FuncMap
+	filters FilterMap
+
+	// scopes are the scopes of the root params and of the items of the
+	// ranges, outermost first.
+	scopes          []checkScope
+	rangeSourceType reflect.Type
+
+	errs TypeCheckErrors
+}
+
+// checkScope has the types of the names that a range adds to a scope and,
+// if isMerged, the type of the params that are merged into it. A nil type
+// is unknown.
+type checkScope struct {
+	aliases  map[string]reflect.Type
+	params   reflect.Type
+	isMerged bool
+}
+
+var rangeLoopType = reflect.TypeOf(&RangeLoop{})
+
+func (c *typeChecker) enterRange(node *Node, rangeExt *RangeExtension, modifiers []pipelineFilterCall) {
+	var keyType, itemType reflect.Type
+	var keyed bool
+	if c.rangeSourceType != nil {
+		// the source was already reported if it cannot be ranged over
+		keyType, itemType, keyed, _ = rangeItemTypes(c.rangeSourceType)
+		keyType, itemType, keyed = rangeModifierTypes(modifiers, keyType, itemType, keyed)
+	}
+	if keyType != nil {
+		keyType = knownType(keyType)
+	}
+	if itemType != nil {
+		itemType = knownType(itemType)
+	}
+
+	// the `$loop` is not available to the conditions of the modifiers
+	aliases := make(map[string]reflect.Type)
+	if len(modifiers) == len(rangeExt.modifiers) {
+		aliases[LoopParam] = rangeLoopType
+	}
+	switch {
+	case rangeExt.secondAlias != "" && keyed:
+		aliases[rangeExt.alias], aliases[rangeExt.secondAlias] = keyType, itemType
+	case rangeExt.secondAlias != "":
+		aliases[rangeExt.alias], aliases[rangeExt.secondAlias] = itemType, keyType
+	case rangeExt.alias != "":
+		aliases[rangeExt.alias] = itemType
+	}
+	if rangeExt.alias != "" || rangeExt.isRepeat {
+		c.scopes = append(c.scopes, checkScope{aliases: aliases})
+		return
+	}
+	if itemType != nil {
+		if err := checkParamsType(itemType); err != nil && len(modifiers) < len(rangeExt.modifiers) {
+			// it is reported once, when the range itself is entered
+			itemType = nil
+		} else if err != nil {
+			c.errs = append(c.errs, &TypeCheckError{
+				Position:   node.Position(),
+				Expression: rangeExt.sourceVarName,
+				Message: fmt.Sprintf("cannot range over %v without naming its items: %v",
+					c.rangeSourceType, err),
+			})
+			itemType = nil
+		}
+	}
+	c.scopes = append(c.scopes, checkScope{aliases: aliases, params: itemType, isMerged: true})
+}
+
+func (c *typeChecker) leaveRange() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *typeChecker) visitExpression(node *Node, input string, kind expressionKind) error {
+	if kind == rangeExpression {
+		c.rangeSourceType = nil
+	}
+	addError := func(format string, args ...interface{}) {
+		c.errs = append(c.errs, &TypeCheckError{
+			Position:   node.Position(),
+			Expression: input,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	p, err := parsePipeline(input)
+	if err != nil {
+		addError("%v", err)
+		return nil
+	}
+
+	resultType, errs := c.expressionType(p.expression)
+	for _, err := range errs {
+		addError("%v", err)
+	}
+	for _, filterCall := range p.filters {
+		if _, hasFilter := c.filters[filterCall.name]; !hasFilter {
+			addError("unknown filter `%v`", filterCall.name)
+		}
+		for _, arg := range filterCall.args {
+			_, errs := c.expressionType(arg)
+			for _, err := range errs {
+				addError("%v", err)
+			}
+		}
+		if !typePreservingFilters[filterCall.name] {
+			resultType = nil
+		}
+	}
+	if resultType == nil {
+		return nil
+	}
+
+	switch kind {
+	case conditionExpression:
+		if resultType.Kind() != reflect.Bool {
+			addError("expecting a condition, got %v", resultType)
+		}
+	case countExpression:
+		if !isIntKind(resultType.Kind()) {
+			addError("expecting a count, got %v", resultType)
+		}
+	case rangeExpression:
+		if _, _, _, err := rangeItemTypes(resultType); err != nil {
+			addError("%v", err)
+		} else {
+			c.rangeSourceType = resultType
+		}
+	}
+	return nil
+}
+
+// expressionType checks the variables, member access paths and calls of
+// the expression and returns its type if it can be known.
+func (c *typeChecker) expressionType(input string) (reflect.Type, []error) {
+	root, err := parseExpression(input)
+	if err != nil {
+		return nil, []error{err}
+	}
+	tc := exprTypeChecker{typeChecker: c, input: input}
+	t := tc.nodeType(root)
+	return t, tc.errs
+}
+
+type exprTypeChecker struct {
+	*typeChecker
+	input string
+	errs  []error
+}
+
+var (
+	boolType   = reflect.TypeOf(false)
+	intType    = reflect.TypeOf(0)
+	floatType  = reflect.TypeOf(float64(0))
+	stringType = reflect.TypeOf("")
+)
+
+func (tc *exprTypeChecker) text(node exprNode) string {
+	span := node.span()
+	return tc.input[span.start:span.end]
+}
+
+func (tc *exprTypeChecker) addError(err error) {
+	tc.errs = append(tc.errs, err)
+}
+
+// nodeType returns the type of the node or nil if it is unknown.
+func (tc *exprTypeChecker) nodeType(node exprNode) reflect.Type {
+	switch node := node.(type) {
+	case *exprLiteral:
+		if node.value == nil {
+			return nil
+		}
+		return reflect.TypeOf(node.value)
+	case *exprIdent:
+		t, hasVariable := tc.variableType(node.name)
+		if !hasVariable {
+			tc.addError(fmt.Errorf("undefined variable `%v`", node.name))
+		}
+		return t
+	case *exprMember:
+		t, err := memberType(tc.nodeType(node.target), node.name)
+		if err != nil {
+			tc.addError(fmt.Errorf("`%v`: %v", tc.text(node.target), err))
+		}
+		return t
+	case *exprIndex:
+		return tc.indexType(node)
+	case *exprCall:
+		return tc.callType(node)
+	case *exprUnary:
+		operandType := tc.nodeType(node.operand)
+		if node.op == "!" {
+			tc.expectBool(node.operand, operandType)
+			return boolType
+		}
+		return operandType
+	case *exprBinary:
+		return tc.binaryType(node)
+	case *exprTernary:
+		tc.expectBool(node.condition, tc.nodeType(node.condition))
+		thenType, otherwiseType := tc.nodeType(node.then), tc.nodeType(node.otherwise)
+		if thenType == otherwiseType {
+			return thenType
+		}
+		return nil
+	case *exprList:
+		for _, item := range node.items {
+			tc.nodeType(item)
+		}
+		return reflect.TypeOf([]interface{}{})
+	case *exprObject:
+		for _, value := range node.values {
+			tc.nodeType(value)
+		}
+		return reflect.TypeOf(map[string]interface{}{})
+	default:
+		return nil
+	}
+}
+
+func (tc *exprTypeChecker) expectBool(node exprNode, t reflect.Type) {
+	if t != nil && t.Kind() != reflect.Bool {
+		tc.addError(fmt.Errorf("`%v`: expecting a boolean, got %v", tc.text(node), t))
+	}
+}
+
+func (tc *exprTypeChecker) indexType(node *exprIndex) reflect.Type {
+	t := tc.nodeType(node.target)
+	tc.nodeType(node.index)
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return knownType(t.Elem())
+	case reflect.String:
+		return stringType
+	case reflect.Struct:
+		if name, isLiteral := node.index.(*exprLiteral); isLiteral {
+			if name, isName := name.value.(string); isName {
+				memberT, err := memberType(t, name)
+				if err != nil {
+					tc.addError(fmt.Errorf("`%v`: %v", tc.text(node.target), err))
+				}
+				return memberT
+			}
+		}
+		return nil
+	default:
+		tc.addError(fmt.Errorf("`%v`: cannot index %v", tc.text(node.target), t))
+		return nil
+	}
+}
+
+func (tc *exprTypeChecker) callType(node *exprCall) reflect.Type {
+	for _, arg := range node.args {
+		tc.nodeType(arg)
+	}
+
+	var fnType reflect.Type
+	switch callee := node.callee.(type) {
+	case *exprIdent:
+		if fn, isFunc := tc.funcs[callee.name]; isFunc {
+			fnType = reflect.TypeOf(fn)
+		} else if t, hasVariable := tc.variableType(callee.name); hasVariable {
+			fnType = t
+		} else {
+			tc.addError(fmt.Errorf("unknown function `%v`", callee.name))
+			return nil
+		}
+	case *exprMember:
+		t, err := memberType(tc.nodeType(callee.target), callee.name)
+		if err != nil {
+			tc.addError(fmt.Errorf("`%v`: %v", tc.text(callee.target), err))
+			return nil
+		}
+		fnType = t
+	default:
+		fnType = tc.nodeType(callee)
+	}
+
+	resultType, err := callResultType(tc.text(node.callee), fnType, len(node.args))
+	if err != nil {
+		tc.addError(err)
+	}
+	return resultType
+}
+
+func (tc *exprTypeChecker) binaryType(node *exprBinary) reflect.Type {
+	leftType, rightType := tc.nodeType(node.left), tc.nodeType(node.right)
+	switch node.op {
+	case "&&", "||":
+		tc.expectBool(node.left, leftType)
+		tc.expectBool(node.right, rightType)
+		return boolType
+	case "==", "!=", "<", "<=", ">", ">=", "in":
+		return boolType
+	case "..":
+		for _, operand := range []struct {
+			node exprNode
+			t    reflect.Type
+		}{{node.left, leftType}, {node.right, rightType}} {
+			if operand.t != nil && !isIntKind(operand.t.Kind()) {
+				tc.addError(fmt.Errorf("`%v`: expecting an integer, got %v", tc.text(operand.node), operand.t))
+			}
+		}
+		return intRangeType
+	case "??":
+		if leftType == rightType {
+			return leftType
+		}
+		return nil
+	}
+	if leftType == nil || rightType == nil {
+		return nil
+	}
+	if node.op == "+" && (leftType.Kind() == reflect.String || rightType.Kind() == reflect.String) {
+		return stringType
+	}
+	if !isNumberKind(leftType.Kind()) || !isNumberKind(rightType.Kind()) {
+		tc.addError(fmt.Errorf("`%v`: cannot use `%v` on %v and %v", tc.text(node), node.op, leftType, rightType))
+		return nil
+	}
+	if isIntKind(leftType.Kind()) && isIntKind(rightType.Kind()) {
+		return intType
+	}
+	return floatType
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	return isNumberKind(kind) && kind != reflect.Float32 && kind != reflect.Float64
+}
+
+// variableType looks for the variable starting from the innermost scope.
+func (c *typeChecker) variableType(name string) (reflect.Type, bool) {
+	for scopeIdx := len(c.scopes) - 1; scopeIdx >= 0; scopeIdx-- {
+		scope := c.scopes[scopeIdx]
+		if aliasType, isAlias := scope.aliases[name]; isAlias {
+			return aliasType, true
+		}
+		if !scope.isMerged {
+			continue
+		}
+		t := scope.params
+		if t == nil {
+			return nil, true
+		}
+		if method, hasMethod := methodType(t, name); hasMethod {
+			return method, true
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Interface:
+			return nil, true
+		case reflect.Map:
+			return knownType(t.Elem()), true
+		case reflect.Struct:
+			if field, hasField := structFieldByName(t, name); hasField {
+				return knownType(field.Type), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func callResultType(name string, t reflect.Type, argCount int) (reflect.Type, error) {
+	if t == nil {
+		return nil, nil
+	}
+	if t.Kind() != reflect.Func {
+		return nil, fmt.Errorf("`%v` is not a function", name)
+	}
+	if t.IsVariadic() && argCount < t.NumIn()-1 {
+		return nil, fmt.Errorf("function `%v` expects at least %d argument(s), got %d", name, t.NumIn()-1, argCount)
+	} else if !t.IsVariadic() && argCount != t.NumIn() {
+		return nil, fmt.Errorf("function `%v` expects %d argument(s), got %d", name, t.NumIn(), argCount)
+	}
+	if t.NumOut() == 0 {
+		return nil, nil
+	}
+	return knownType(t.Out(0)), nil
+}
+
+// memberType is the static counterpart of resolveMember.
+func memberType(t reflect.Type, name string) (reflect.Type, error) {
+	if t == nil {
+		return nil, nil
+	}
+	if method, hasMethod := methodType(t, name); hasMethod {
+		return method, nil
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return nil, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot access `%v` of %v because its keys are not strings", name, t)
+		}
+		return knownType(t.Elem()), nil
+	case reflect.Struct:
+		field, hasField := structFieldByName(t, name)
+		if !hasField {
+			if !isExportedName(name) {
+				return nil, fmt.Errorf("cannot access the unexported field or method `%v` of %v", name, t)
+			}
+			return nil, fmt.Errorf("%v does not have a field or method named `%v`", t, name)
+		}
+		return knownType(field.Type), nil
+	default:
+		return nil, fmt.Errorf("cannot access `%v` of %v", name, t)
+	}
+}
+
+// methodType returns the type of the method value, which does not have
+// the receiver as its first parameter. Like methodByName, it includes
+// the methods with pointer receivers.
+func methodType(t reflect.Type, name string) (reflect.Type, bool) {
+	if !isExportedName(name) || t.Kind() == reflect.Interface {
+		return nil, false
+	}
+	if t.Kind() != reflect.Ptr {
+		t = reflect.PtrTo(t)
+	}
+	method, hasMethod := t.MethodByName(name)
+	if !hasMethod {
+		return nil, false
+	}
+
+	in := make([]reflect.Type, method.Type.NumIn()-1)
+	for inIdx := range in {
+		in[inIdx] = method.Type.In(inIdx + 1)
+	}
+	out := make([]reflect.Type, method.Type.NumOut())
+	for outIdx := range out {
+		out[outIdx] = method.Type.Out(outIdx)
+	}
+	return reflect.FuncOf(in, out, method.Type.IsVariadic()), true
+}
+
+// knownType returns nil for interface types because the type of their
+// values is only known while rendering.
+func knownType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	return t
+}
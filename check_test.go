@@ -0,0 +1,216 @@
+package tplinator_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+type checkedItem struct {
+	Title    string
+	Price    int
+	Tags     []string
+	Variants []checkedVariant
+}
+
+func (ci *checkedItem) IsOnSale(percent int) bool {
+	return false
+}
+
+type checkedVariant struct {
+	Name    string `json:"name"`
+	InStock bool
+}
+
+type checkedPage struct {
+	Title    string
+	LoggedIn bool
+	User     *user
+	Items    []checkedItem
+	Extra    map[string]interface{}
+	Count    int
+}
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+
+		expectedErrors []string
+	}{
+		{
+			name: "valid template",
+			template: `<div><h1 go-if="LoggedIn && User.HasRole('admin')">{{go:User.Profile.DisplayName}}</h1>` +
+				`<h1 go-elif="Count > 0">{{go:Title | upper}}</h1>` +
+				`<ul go-range="Items | sortBy('Price')" go-if-class-sale="IsOnSale(10)">` +
+				`<li go-range="Variants" go-if-class-stock="InStock">{{go:name}} of {{go:Title}} {{go:Extra.anything.goes}}</li>` +
+				`</ul><p go-if="hasRole(User.Roles, 'admin')">{{go:Items[0].Variants[1].name ?? Title}}</p>` +
+				`<p go-if="Count > 1 ? LoggedIn : 'x' in User.Roles">{{go:Count / 2 + Items[0].Price}}</p></div>`,
+		},
+		{
+			name: "mismatches",
+			template: "<div>\n" +
+				`<h1 go-if="Title">{{go:Titel}}</h1>` + "\n" +
+				`<p go-if="User.Profile.nickname == ''">{{go:User.Age}}</p>` + "\n" +
+				`<ul go-range="Title"><li>{{go:Price.Amount}}</li></ul>` + "\n" +
+				`<i go-range="Items" go-if-class-a="Price">{{go:IsOnSale()}} {{go:unknown(Count)}} {{go:Count | shout}}</i>` + "\n" +
+				`<a href="{{go:hasRole(User.Roles)}}" go-if="Count && Items[0].Nope"></a>` + "\n" +
+				"</div>",
+			expectedErrors: []string{
+				"line 2, column 1: `Title`: expecting a condition, got string",
+				"line 2, column 19: `Titel`: undefined variable `Titel`",
+				"line 3, column 1: `User.Profile.nickname == ''`: `User.Profile`: cannot access the unexported field or method `nickname` of tplinator_test.profile",
+				"line 3, column 40: `User.Age`: `User`: tplinator_test.user does not have a field or method named `Age`",
+				"line 4, column 1: `Title`: cannot range over string",
+				"line 5, column 1: `Price`: expecting a condition, got int",
+				"line 5, column 43: `IsOnSale()`: function `IsOnSale` expects 1 argument(s), got 0",
+				"line 5, column 43: `unknown(Count)`: unknown function `unknown`",
+				"line 5, column 43: `Count | shout`: unknown filter `shout`",
+				"line 6, column 1: `Count && Items[0].Nope`: `Items[0]`: tplinator_test.checkedItem does not have a field or method named `Nope`",
+				"line 6, column 1: `Count && Items[0].Nope`: `Count`: expecting a boolean, got int",
+				"line 6, column 1: `hasRole(User.Roles)`: function `hasRole` expects 2 argument(s), got 1",
+			},
+		},
+		{
+			name: "range aliases",
+			template: "<div>\n" +
+				`<ul go-range="item in Items"><li go-range="tag in item.Tags">{{go:tag | upper}} {{go:item.Nope}} {{go:Title}}</li></ul>` + "\n" +
+				`<p go-range="Items[0].Tags">{{go:tag}}</p><p go-range="key in Extra">{{go:key.anything}}</p>` + "\n" +
+				"</div>",
+			expectedErrors: []string{
+				"line 2, column 62: `item.Nope`: `item`: tplinator_test.checkedItem does not have a field or method named `Nope`",
+				"line 3, column 1: `Items[0].Tags`: cannot range over []string without naming its items: cannot use string as params",
+			},
+		},
+		{
+			name:     "range empty fallback",
+			template: `<ul><li go-range="item in Items">{{go:item.Title}}</li><li go-range-empty>{{go:Titel}}</li></ul>`,
+			expectedErrors: []string{
+				"line 1, column 75: `Titel`: undefined variable `Titel`",
+			},
+		},
+		{
+			name:     "numeric ranges",
+			template: `<p><i go-range="i in 1..Count">{{go:i + 1}}</i><b go-range="range(0, Count, 2)">x</b><u go-repeat="Title">{{go:$loop.index}}</u><s go-range="n in 1..Title"></s></p>`,
+			expectedErrors: []string{
+				"line 1, column 48: `range(0, Count, 2)`: cannot range over func(func(int) bool) without naming its items: cannot use int as params",
+				"line 1, column 86: `Title`: expecting a count, got string",
+				"line 1, column 129: `1..Title`: `Title`: expecting an integer, got string",
+			},
+		},
+		{
+			name: "range modifiers",
+			template: `<div go-range="row in Items | where(row.Price > 0) | chunk(2)"><p go-range="item in row">{{go:item.Nope}}</p></div>` +
+				`<ul go-range="g in Items | where(g.Nope) | groupBy('Title') | limit(Count)"><li>{{go:g.key}} {{go:$loop.index}} {{go:g.nope}}</li></ul>`,
+			expectedErrors: []string{
+				"line 1, column 90: `item.Nope`: `item`: tplinator_test.checkedItem does not have a field or method named `Nope`",
+				"line 1, column 116: `g.Nope`: `g`: tplinator_test.checkedItem does not have a field or method named `Nope`",
+				"line 1, column 196: `g.nope`: `g`: cannot access the unexported field or method `nope` of tplinator.RangeGroup",
+			},
+		},
+		{
+			name:     "switch cases",
+			template: `<div go-switch="User.Nme"><p go-case="1, Cnt">one</p><p go-default>{{go:Titel}}</p></div>`,
+			expectedErrors: []string{
+				"line 1, column 1: `User.Nme`: `User`: tplinator_test.user does not have a field or method named `Nme`",
+				"line 1, column 27: `Cnt`: undefined variable `Cnt`",
+				"line 1, column 68: `Titel`: undefined variable `Titel`",
+			},
+		},
+		{
+			name:     "attribute bindings",
+			template: `<input :value="User.Nme" go-checked="LoggedIn" go-disabled="Count" :title="Count"/>`,
+			expectedErrors: []string{
+				"line 1, column 1: `Count`: expecting a condition, got int",
+				"line 1, column 1: `User.Nme`: `User`: tplinator_test.user does not have a field or method named `Nme`",
+			},
+		},
+		{
+			name:     "class bindings",
+			template: `<div go-if-class-wide="Count" go-class="['card', {active: LoggedIn}, Titel]">x</div>`,
+			expectedErrors: []string{
+				"line 1, column 1: `Count`: expecting a condition, got int",
+				"line 1, column 1: `['card', {active: LoggedIn}, Titel]`: undefined variable `Titel`",
+			},
+		},
+		{
+			name:     "text",
+			template: `<p go-text="User.Nme">{{go:Placeholder}}</p>`,
+			expectedErrors: []string{
+				"line 1, column 1: `User.Nme`: `User`: tplinator_test.user does not have a field or method named `Nme`",
+			},
+		},
+		{
+			name:     "translation",
+			template: `<p go-t="welcome" go-t-params="{name: User.Nme}">{{go:Nope}}</p>`,
+			expectedErrors: []string{
+				"line 1, column 1: `{name: User.Nme}`: `User`: tplinator_test.user does not have a field or method named `Nme`",
+			},
+		},
+		{
+			name: "loop variables",
+			template: `<ul go-range="item, i in Items"><li go-range="name, value in Extra">` +
+				`{{go:i + $loop.parent.index}} {{go:name | upper}} {{go:item.Price + $loop.length}} {{go:$loop.nope}}</li></ul>`,
+			expectedErrors: []string{
+				"line 1, column 69: `$loop.nope`: `$loop`: cannot access the unexported field or method `nope` of tplinator.RangeLoop",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(
+				strings.NewReader(tc.template),
+				tplinator.FuncsParserOption(tplinator.FuncMap{
+					"hasRole": func(roles []string, role string) bool { return false },
+				}),
+			)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			err = tplinator.Check(tpl, reflect.TypeOf(checkedPage{}))
+			if len(tc.expectedErrors) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var typeCheckErrs tplinator.TypeCheckErrors
+			if !errors.As(err, &typeCheckErrs) {
+				t.Errorf("expecting type check errors, got `%v`", err)
+				return
+			}
+			var actualErrors []string
+			for _, typeCheckErr := range typeCheckErrs {
+				actualErrors = append(actualErrors, typeCheckErr.Error())
+			}
+			if !reflect.DeepEqual(actualErrors, tc.expectedErrors) {
+				t.Errorf("wanted errors:\n%v\ngot:\n%v",
+					strings.Join(tc.expectedErrors, "\n"), strings.Join(actualErrors, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheck_ParamsType(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<p>{{go:name}}</p>`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if err := tplinator.Check(tpl, reflect.TypeOf(tplinator.EvaluatorParams{})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := tplinator.Check(tpl, reflect.TypeOf(&checkedPage{})); err == nil {
+		t.Error("expecting an error because `name` is not a field of checkedPage")
+	}
+	if err := tplinator.Check(tpl, reflect.TypeOf("")); err == nil {
+		t.Error("expecting an error because a string cannot be used as params")
+	}
+}
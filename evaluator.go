@@ -1,7 +1,10 @@
 package tplinator
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/Knetic/govaluate"
 )
@@ -19,6 +22,43 @@ type Evaluator interface {
 	Evaluate(input string, params EvaluatorParams) (interface{}, error)
 }
 
+type EvaluatorOptions struct {
+	Funcs FuncMap
+	// MaxOperations limits the number of operations of an evaluation. An
+	// evaluation that exceeds it fails with a *LimitError.
+	MaxOperations int
+	// MissingKey is the policy for the variables that do not exist. If it
+	// allows them, a missing variable is the zero value of the type that
+	// the expression expects instead of failing the evaluation.
+	MissingKey MissingKeyPolicy
+}
+
+type ConfigurableEvaluator interface {
+	Evaluator
+	WithOptions(options EvaluatorOptions) Evaluator
+}
+
+// EvaluatorParserOption sets the evaluator of the template, e.g.
+// NewNativeEvaluator() to opt into the native expression language. It
+// takes precedence over the evaluator of the extension dependencies.
+func EvaluatorParserOption(evaluator Evaluator) ParserOptionFunc {
+	return func(p *Parser) {
+		p.evaluator = evaluator
+	}
+}
+
+// scopeEvaluator is implemented by the built-in evaluators, which can
+// evaluate an expression using a scope chain without merging it.
+type scopeEvaluator interface {
+	evaluateScope(input string, scope scopeChain) (interface{}, error)
+}
+
+// TryEvaluateBoolUsingContext evaluates a boolean expression using each
+// of the context params of the source until one of the evaluations
+// succeeds. It returns the error of the last evaluation if none of them
+// does.
+//
+// Deprecated: use Node#Scope and evaluate the expression once instead.
 func TryEvaluateBoolUsingContext(ecs EvaluatorContextSource, evaluator Evaluator, inputStr string) (bool, bool, error) {
 	var result bool
 	var err error
@@ -31,6 +71,12 @@ func TryEvaluateBoolUsingContext(ecs EvaluatorContextSource, evaluator Evaluator
 	return false, false, err
 }
 
+// TryEvaluateStringUsingContext evaluates an expression into a string
+// using each of the context params of the source until one of the
+// evaluations succeeds. It returns the error of the last evaluation if
+// none of them does.
+//
+// Deprecated: use Node#Scope and evaluate the expression once instead.
 func TryEvaluateStringUsingContext(ecs EvaluatorContextSource, evaluator Evaluator, inputStr string) (bool, string, error) {
 	var result string
 	var err error
@@ -43,6 +89,11 @@ func TryEvaluateStringUsingContext(ecs EvaluatorContextSource, evaluator Evaluat
 	return false, "", err
 }
 
+// TryEvaluateUsingContext evaluates an expression using each of the
+// context params of the source until one of the evaluations succeeds. It
+// returns the error of the last evaluation if none of them does.
+//
+// Deprecated: use Node#Scope and evaluate the expression once instead.
 func TryEvaluateUsingContext(ecs EvaluatorContextSource, evaluator Evaluator, inputStr string) (bool, interface{}, error) {
 	var result interface{}
 	var err error
@@ -56,14 +107,340 @@ func TryEvaluateUsingContext(ecs EvaluatorContextSource, evaluator Evaluator, in
 }
 
 type govaluator struct {
+	functions  map[string]govaluate.ExpressionFunction
+	missingKey MissingKeyPolicy
+	native     *nativeEvaluator
 }
 
-func (e *govaluator) EvaluateBool(input string, params EvaluatorParams) (bool, error) {
-	expr, err := govaluate.NewEvaluableExpression(input)
+func (e *govaluator) WithOptions(options EvaluatorOptions) Evaluator {
+	functions := make(map[string]govaluate.ExpressionFunction, len(options.Funcs))
+	for name, fn := range options.Funcs {
+		functions[name] = govaluateFunction(name, reflect.ValueOf(fn))
+	}
+	return &govaluator{
+		functions:  functions,
+		missingKey: options.MissingKey,
+		native:     e.native.WithOptions(options).(*nativeEvaluator),
+	}
+}
+
+func govaluateFunction(name string, fn reflect.Value) govaluate.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		result, err := callFunc(name, fn, args)
+		if err != nil {
+			return nil, fmt.Errorf("evaluator: %v", err)
+		}
+		// govaluate can only compare and do arithmetic on float64 numbers
+		if number, err := toFloat64(result); err == nil {
+			return number, nil
+		}
+		return result, nil
+	}
+}
+
+// govaluateParameters resolves the member access paths that were turned
+// into escaped variables (e.g. `[user.Profile.DisplayName]`) by
+// govaluator#prepare.
+type govaluateParameters struct {
+	scope scopeChain
+
+	// if the policy allows missing variables, they are the zero value of
+	// the type that the tokens around them expect
+	missingKey MissingKeyPolicy
+	tokens     []govaluate.ExpressionToken
+	missing    *missingValue
+}
+
+func (gp *govaluateParameters) Get(name string) (interface{}, error) {
+	value, err := gp.resolve(name)
+	var missingVarErr *MissingVariableError
+	if err == nil || !errors.As(err, &missingVarErr) {
+		return value, err
+	}
+	switch gp.missingKey {
+	case MissingKeyZero, MissingKeyPlaceholder:
+	default:
+		return nil, err
+	}
+	missing := missingValue{name: missingVarErr.Name, policy: gp.missingKey}
+	if gp.missing == nil {
+		gp.missing = &missing
+	}
+	return gp.zeroValue(name, missing), nil
+}
+
+// zeroValue guesses the zero value of a missing variable from the tokens
+// around it, because the operators of govaluate do not accept nil. It is
+// the zero value of the type of the other operand of a comparison or an
+// arithmetic operator, false for the operands of the logical operators
+// and nil otherwise. The parentheses around the operands are ignored.
+func (gp *govaluateParameters) zeroValue(name string, missing missingValue) interface{} {
+	tokenIdx := -1
+	for idx, token := range gp.tokens {
+		if token.Kind == govaluate.VARIABLE && token.Value == name {
+			tokenIdx = idx
+			break
+		}
+	}
+	if tokenIdx < 0 {
+		return nil
+	}
+
+	// the operand spans the parentheses that enclose only the variable,
+	// unless they are the ones of a function call
+	start, end := tokenIdx, tokenIdx
+	for start > 0 && end < len(gp.tokens)-1 &&
+		gp.tokens[start-1].Kind == govaluate.CLAUSE && gp.tokens[end+1].Kind == govaluate.CLAUSE_CLOSE &&
+		(start < 2 || gp.tokens[start-2].Kind != govaluate.FUNCTION) {
+		start, end = start-1, end+1
+	}
+
+	if start > 0 && gp.tokens[start-1].Kind == govaluate.PREFIX {
+		if gp.tokens[start-1].Value == "!" {
+			return false
+		}
+		return 0.0
+	}
+
+	// the operator after the variable takes precedence over the one before
+	for _, step := range []int{1, -1} {
+		opIdx := end + 1
+		if step < 0 {
+			opIdx = start - 1
+		}
+		if opIdx < 0 || opIdx >= len(gp.tokens) {
+			continue
+		}
+		op := gp.tokens[opIdx]
+		switch op.Kind {
+		case govaluate.LOGICALOP:
+			return false
+		case govaluate.TERNARY:
+			if step > 0 && op.Value == "?" {
+				return false
+			}
+		case govaluate.MODIFIER, govaluate.COMPARATOR:
+			otherIdx := opIdx + step
+			for otherIdx >= 0 && otherIdx < len(gp.tokens) &&
+				(gp.tokens[otherIdx].Kind == govaluate.CLAUSE || gp.tokens[otherIdx].Kind == govaluate.CLAUSE_CLOSE) {
+				otherIdx += step
+			}
+			if otherIdx < 0 || otherIdx >= len(gp.tokens) {
+				continue
+			}
+			other := gp.tokens[otherIdx]
+			var otherValue interface{}
+			switch other.Kind {
+			case govaluate.STRING, govaluate.NUMERIC, govaluate.BOOLEAN, govaluate.TIME:
+				otherValue = other.Value
+			case govaluate.VARIABLE:
+				otherValue, _ = gp.resolve(other.Value.(string))
+			}
+			switch {
+			case op.Value == "+" && (otherValue == nil || other.Kind == govaluate.STRING):
+				if _, isString := otherValue.(string); isString || other.Kind == govaluate.VARIABLE {
+					return missing.String()
+				}
+				return 0.0
+			case otherValue == nil:
+				if op.Kind == govaluate.MODIFIER {
+					return 0.0
+				}
+				return nil
+			}
+			if _, isNumber := toNumber(otherValue); isNumber {
+				return 0.0
+			}
+			if _, isString := otherValue.(string); isString && op.Value == "+" {
+				return missing.String()
+			}
+			return reflect.Zero(reflect.TypeOf(otherValue)).Interface()
+		}
+	}
+	return nil
+}
+
+func (gp *govaluateParameters) resolve(name string) (interface{}, error) {
+	if value, hasValue := gp.scope.lookup(name); hasValue {
+		return value, nil
+	}
+	path := strings.Split(name, ".")
+	root, hasRoot := gp.scope.lookup(path[0])
+	if !hasRoot {
+		return nil, &MissingVariableError{Name: path[0]}
+	}
+	value, err := resolvePath(root, path[1:])
 	if err != nil {
-		return false, err
+		if errors.As(err, &memberNotFoundError{}) {
+			return nil, &MissingVariableError{Name: name}
+		}
+		return nil, fmt.Errorf("evaluator: `%v`: %v", name, err)
 	}
-	result, err := expr.Evaluate(params)
+	return value, nil
+}
+
+// prepare rewrites the parts of the input that govaluate cannot parse.
+// Member access paths become escaped variables and the calls to methods
+// and to functions that are not registered become calls to functions
+// that are created for this evaluation only.
+func (e *govaluator) prepare(input string, params *govaluateParameters) (string, map[string]govaluate.ExpressionFunction, error) {
+	tokens, err := scanExpression(input)
+	if err != nil {
+		return "", nil, fmt.Errorf("evaluator: %v", err)
+	}
+
+	functions := e.functions
+	addFunction := func(path []string) string {
+		if len(functions) == len(e.functions) {
+			functions = make(map[string]govaluate.ExpressionFunction, len(e.functions)+1)
+			for name, fn := range e.functions {
+				functions[name] = fn
+			}
+		}
+		name := fmt.Sprintf("tplinatorCall%d", len(functions)-len(e.functions))
+		functions[name] = func(args ...interface{}) (interface{}, error) {
+			var result interface{}
+			var err error
+			if len(path) == 1 {
+				fn, hasFn := params.scope.lookup(path[0])
+				if !hasFn {
+					return nil, fmt.Errorf("evaluator: unknown function `%v` in `%v`", path[0], input)
+				}
+				result, err = callFunc(path[0], reflect.ValueOf(fn), args)
+			} else {
+				var receiver interface{}
+				receiver, err = params.Get(strings.Join(path[:len(path)-1], "."))
+				if err != nil {
+					return nil, err
+				}
+				result, err = callMethod(receiver, path[len(path)-1], args)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("evaluator: `%v`: %v", strings.Join(path, "."), err)
+			}
+			if number, err := toFloat64(result); err == nil {
+				return number, nil
+			}
+			return result, nil
+		}
+		return name
+	}
+
+	var sb strings.Builder
+	lastEnd := 0
+	for tokenIdx := 0; tokenIdx < len(tokens); tokenIdx++ {
+		token := tokens[tokenIdx]
+
+		// leave the escaped variables untouched
+		if token.is(exprPunctToken, "[") {
+			for tokenIdx < len(tokens) && !tokens[tokenIdx].is(exprPunctToken, "]") {
+				tokenIdx++
+			}
+			continue
+		}
+		if token.kind != exprIdentToken {
+			continue
+		}
+		switch token.text {
+		case "true", "false", "in", "IN":
+			continue
+		}
+
+		path := []string{token.text}
+		pathEnd := token.end
+		for tokenIdx+2 < len(tokens) && tokens[tokenIdx+1].is(exprPunctToken, ".") &&
+			tokens[tokenIdx+2].kind == exprIdentToken {
+			path = append(path, tokens[tokenIdx+2].text)
+			pathEnd = tokens[tokenIdx+2].end
+			tokenIdx += 2
+		}
+		isCall := tokenIdx+1 < len(tokens) && tokens[tokenIdx+1].is(exprPunctToken, "(")
+
+		var replacement string
+		if isCall {
+			if _, isFunction := e.functions[token.text]; isFunction && len(path) == 1 {
+				continue
+			}
+			replacement = addFunction(path)
+		} else if len(path) > 1 || strings.Contains(token.text, "$") {
+			replacement = "[" + strings.Join(path, ".") + "]"
+		} else {
+			continue
+		}
+		sb.WriteString(input[lastEnd:token.start])
+		sb.WriteString(replacement)
+		lastEnd = pathEnd
+	}
+	sb.WriteString(input[lastEnd:])
+
+	return sb.String(), functions, nil
+}
+
+// usesNativeSyntax reports whether the expression uses the syntax of the
+// native expression language that govaluate does not have, i.e. `..`,
+// `??`, `?.`, indexes, and list and map literals. A list literal is told
+// apart from an escaped variable like `[user-name]` by its items, which
+// are not only names and dashes.
+func usesNativeSyntax(tokens []exprToken) bool {
+	for tokenIdx, token := range tokens {
+		if token.kind != exprPunctToken {
+			continue
+		}
+		switch token.text {
+		case "..", "??", "?.", "{":
+			return true
+		case "[":
+			if tokenIdx > 0 {
+				previous := tokens[tokenIdx-1]
+				if previous.kind == exprIdentToken || previous.is(exprPunctToken, ")") || previous.is(exprPunctToken, "]") {
+					return true
+				}
+			}
+			isEscapedVariable := false
+			for _, item := range tokens[tokenIdx+1:] {
+				if item.is(exprPunctToken, "]") {
+					break
+				}
+				isEscapedVariable = item.kind == exprIdentToken || item.is(exprPunctToken, "-")
+				if !isEscapedVariable {
+					break
+				}
+			}
+			if !isEscapedVariable {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *govaluator) evaluateScope(input string, scope scopeChain) (interface{}, error) {
+	if tokens, err := scanExpression(input); err == nil && usesNativeSyntax(tokens) {
+		return e.native.evaluateScope(input, scope)
+	}
+
+	govaluateParams := &govaluateParameters{scope: scope, missingKey: e.missingKey}
+	preparedInput, functions, err := e.prepare(input, govaluateParams)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := govaluate.NewEvaluableExpressionWithFunctions(preparedInput, functions)
+	if err != nil {
+		return nil, err
+	}
+	govaluateParams.tokens = expr.Tokens()
+	result, err := expr.Eval(govaluateParams)
+	if err != nil {
+		return nil, err
+	}
+	if govaluateParams.missing != nil && len(govaluateParams.tokens) == 1 {
+		return *govaluateParams.missing, nil
+	}
+	return result, nil
+}
+
+func (e *govaluator) EvaluateBool(input string, params EvaluatorParams) (bool, error) {
+	result, err := e.evaluateScope(input, scopeChain{params})
 	if err != nil {
 		return false, err
 	}
@@ -75,11 +452,7 @@ func (e *govaluator) EvaluateBool(input string, params EvaluatorParams) (bool, e
 }
 
 func (e *govaluator) EvaluateString(input string, params EvaluatorParams) (string, error) {
-	expr, err := govaluate.NewEvaluableExpression(input)
-	if err != nil {
-		return "", err
-	}
-	result, err := expr.Evaluate(params)
+	result, err := e.evaluateScope(input, scopeChain{params})
 	if err != nil {
 		return "", err
 	}
@@ -91,11 +464,7 @@ func (e *govaluator) EvaluateString(input string, params EvaluatorParams) (strin
 }
 
 func (e *govaluator) Evaluate(input string, params EvaluatorParams) (interface{}, error) {
-	expr, err := govaluate.NewEvaluableExpression(input)
-	if err != nil {
-		return nil, err
-	}
-	result, err := expr.Evaluate(params)
+	result, err := e.evaluateScope(input, scopeChain{params})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,490 @@
+package tplinator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type exprSpan struct {
+	start int
+	end   int
+}
+
+func (s exprSpan) span() exprSpan {
+	return s
+}
+
+type exprNode interface {
+	span() exprSpan
+}
+
+type exprLiteral struct {
+	exprSpan
+	value interface{}
+}
+
+type exprIdent struct {
+	exprSpan
+	name string
+}
+
+type exprMember struct {
+	exprSpan
+	target   exprNode
+	name     string
+	optional bool
+}
+
+type exprIndex struct {
+	exprSpan
+	target   exprNode
+	index    exprNode
+	optional bool
+}
+
+type exprCall struct {
+	exprSpan
+	callee exprNode
+	args   []exprNode
+}
+
+type exprUnary struct {
+	exprSpan
+	op      string
+	operand exprNode
+}
+
+type exprBinary struct {
+	exprSpan
+	op    string
+	left  exprNode
+	right exprNode
+}
+
+type exprTernary struct {
+	exprSpan
+	condition exprNode
+	then      exprNode
+	otherwise exprNode
+}
+
+type exprList struct {
+	exprSpan
+	items []exprNode
+}
+
+type exprObject struct {
+	exprSpan
+	keys   []string
+	values []exprNode
+}
+
+// binaryPrecedences lists the binary operators from the lowest to the
+// highest precedence. The ternary operator has a lower precedence than
+// all of them and the unary operators have a higher one.
+var binaryPrecedences = [][]string{
+	{"??"},
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">=", "in"},
+	{".."},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+type exprParser struct {
+	input  string
+	tokens []exprToken
+	pos    int
+}
+
+// parseExpression parses the input using the grammar of the native
+// expression language. See the README for the grammar.
+func parseExpression(input string) (exprNode, error) {
+	tokens, err := scanExpression(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{input: input, tokens: tokens}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("`%v` is an empty expression", input)
+	}
+	node, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.unexpected()
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() (exprToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return exprToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *exprParser) isNext(text string) bool {
+	token, hasToken := p.peek()
+	return hasToken && token.kind != exprStringToken && token.text == text
+}
+
+func (p *exprParser) expect(text string) (exprToken, error) {
+	if !p.isNext(text) {
+		return exprToken{}, p.unexpected()
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *exprParser) unexpected() error {
+	token, hasToken := p.peek()
+	if !hasToken {
+		return fmt.Errorf("`%v` ends unexpectedly", p.input)
+	}
+	return fmt.Errorf("`%v` has an unexpected `%v` at offset %d", p.input, token.text, token.start)
+}
+
+func (p *exprParser) parseTernary() (exprNode, error) {
+	condition, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isNext("?") {
+		return condition, nil
+	}
+	p.pos++
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &exprTernary{
+		exprSpan:  exprSpan{condition.span().start, otherwise.span().end},
+		condition: condition,
+		then:      then,
+		otherwise: otherwise,
+	}, nil
+}
+
+func (p *exprParser) parseBinary(precedence int) (exprNode, error) {
+	if precedence == len(binaryPrecedences) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(precedence + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token, hasToken := p.peek()
+		if !hasToken || token.kind == exprStringToken || token.kind == exprNumberToken ||
+			!containsString(binaryPrecedences[precedence], token.text) {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseBinary(precedence + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &exprBinary{
+			exprSpan: exprSpan{left.span().start, right.span().end},
+			op:       token.text,
+			left:     left,
+			right:    right,
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if token, hasToken := p.peek(); hasToken && token.kind == exprPunctToken &&
+		(token.text == "!" || token.text == "-" || token.text == "+") {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprUnary{
+			exprSpan: exprSpan{token.start, operand.span().end},
+			op:       token.text,
+			operand:  operand,
+		}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token, hasToken := p.peek()
+		if !hasToken || token.kind != exprPunctToken {
+			return node, nil
+		}
+		switch token.text {
+		case ".", "?.":
+			p.pos++
+			if token.text == "?." && p.isNext("[") {
+				// optional index access (e.g. `items?.[0]`)
+				p.pos++
+				index, err := p.parseIndex(node, true)
+				if err != nil {
+					return nil, err
+				}
+				node = index
+				continue
+			}
+			nameToken, hasName := p.peek()
+			if !hasName || nameToken.kind != exprIdentToken {
+				return nil, p.unexpected()
+			}
+			p.pos++
+			node = &exprMember{
+				exprSpan: exprSpan{node.span().start, nameToken.end},
+				target:   node,
+				name:     nameToken.text,
+				optional: token.text == "?.",
+			}
+		case "[":
+			p.pos++
+			index, err := p.parseIndex(node, false)
+			if err != nil {
+				return nil, err
+			}
+			node = index
+		case "(":
+			p.pos++
+			args, closeToken, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			node = &exprCall{
+				exprSpan: exprSpan{node.span().start, closeToken.end},
+				callee:   node,
+				args:     args,
+			}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *exprParser) parseIndex(target exprNode, optional bool) (exprNode, error) {
+	index, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	closeToken, err := p.expect("]")
+	if err != nil {
+		return nil, err
+	}
+	return &exprIndex{
+		exprSpan: exprSpan{target.span().start, closeToken.end},
+		target:   target,
+		index:    index,
+		optional: optional,
+	}, nil
+}
+
+// parseList parses comma-separated expressions until the closing token.
+// A trailing comma is allowed.
+func (p *exprParser) parseList(closing string) ([]exprNode, exprToken, error) {
+	var items []exprNode
+	for !p.isNext(closing) {
+		item, err := p.parseTernary()
+		if err != nil {
+			return nil, exprToken{}, err
+		}
+		items = append(items, item)
+		if !p.isNext(",") {
+			break
+		}
+		p.pos++
+	}
+	closeToken, err := p.expect(closing)
+	if err != nil {
+		return nil, exprToken{}, err
+	}
+	return items, closeToken, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token, hasToken := p.peek()
+	if !hasToken {
+		return nil, p.unexpected()
+	}
+	span := exprSpan{token.start, token.end}
+
+	switch token.kind {
+	case exprNumberToken:
+		p.pos++
+		value, err := parseNumberLiteral(token.text)
+		if err != nil {
+			return nil, fmt.Errorf("`%v` has an invalid number `%v`", p.input, token.text)
+		}
+		return &exprLiteral{exprSpan: span, value: value}, nil
+	case exprStringToken:
+		p.pos++
+		return &exprLiteral{exprSpan: span, value: unquoteStringLiteral(token.text)}, nil
+	case exprIdentToken:
+		p.pos++
+		switch token.text {
+		case "true":
+			return &exprLiteral{exprSpan: span, value: true}, nil
+		case "false":
+			return &exprLiteral{exprSpan: span, value: false}, nil
+		case "nil", "null":
+			return &exprLiteral{exprSpan: span}, nil
+		case "in":
+			return nil, fmt.Errorf("`%v` has an unexpected `in` at offset %d", p.input, token.start)
+		}
+		return &exprIdent{exprSpan: span, name: token.text}, nil
+	}
+
+	switch token.text {
+	case "(":
+		p.pos++
+		node, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case "[":
+		p.pos++
+		items, closeToken, err := p.parseList("]")
+		if err != nil {
+			return nil, err
+		}
+		return &exprList{exprSpan: exprSpan{token.start, closeToken.end}, items: items}, nil
+	case "{":
+		p.pos++
+		return p.parseObject(token)
+	}
+	return nil, p.unexpected()
+}
+
+func (p *exprParser) parseObject(openToken exprToken) (exprNode, error) {
+	object := &exprObject{}
+	for !p.isNext("}") {
+		keyToken, hasKey := p.peek()
+		if !hasKey || (keyToken.kind != exprIdentToken && keyToken.kind != exprStringToken) {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		key := keyToken.text
+		if keyToken.kind == exprStringToken {
+			key = unquoteStringLiteral(key)
+		}
+		if _, err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		object.keys = append(object.keys, key)
+		object.values = append(object.values, value)
+		if !p.isNext(",") {
+			break
+		}
+		p.pos++
+	}
+	closeToken, err := p.expect("}")
+	if err != nil {
+		return nil, err
+	}
+	object.exprSpan = exprSpan{openToken.start, closeToken.end}
+	return object, nil
+}
+
+// parseNumberLiteral returns an int for the literals without a decimal
+// point and a float64 for the rest.
+func parseNumberLiteral(text string) (interface{}, error) {
+	text = strings.ReplaceAll(text, "_", "")
+	if strings.Contains(text, ".") {
+		return strconv.ParseFloat(text, 64)
+	}
+	number, err := strconv.ParseInt(text, 10, 0)
+	return int(number), err
+}
+
+func unquoteStringLiteral(text string) string {
+	quote := text[0]
+	text = text[1 : len(text)-1]
+	if quote == '`' {
+		return text
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			sb.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(text[i])
+		}
+	}
+	return sb.String()
+}
+
+// walkExprNodes visits the node then its children unless visit returns
+// false.
+func walkExprNodes(node exprNode, visit func(exprNode) bool) {
+	if !visit(node) {
+		return
+	}
+	var children []exprNode
+	switch node := node.(type) {
+	case *exprMember:
+		children = []exprNode{node.target}
+	case *exprIndex:
+		children = []exprNode{node.target, node.index}
+	case *exprCall:
+		children = append([]exprNode{node.callee}, node.args...)
+	case *exprUnary:
+		children = []exprNode{node.operand}
+	case *exprBinary:
+		children = []exprNode{node.left, node.right}
+	case *exprTernary:
+		children = []exprNode{node.condition, node.then, node.otherwise}
+	case *exprList:
+		children = node.items
+	case *exprObject:
+		children = node.values
+	}
+	for _, child := range children {
+		walkExprNodes(child, visit)
+	}
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package tplinator
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+type exprTokenKind int
+
+const (
+	exprIdentToken exprTokenKind = iota
+	exprNumberToken
+	exprStringToken
+	exprPunctToken
+)
+
+type exprToken struct {
+	kind  exprTokenKind
+	text  string
+	start int
+	end   int
+}
+
+func (t exprToken) is(kind exprTokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+// scanExpression splits an expression into tokens. It only knows enough
+// about the expression syntax to tell identifiers, literals and the other
+// symbols apart so that expressions can be inspected without evaluating
+// them.
+func scanExpression(input string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '_' || r == '$' || unicode.IsLetter(r):
+			start := i
+			for i < len(input) {
+				r, size = utf8.DecodeRuneInString(input[i:])
+				if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, exprToken{kind: exprIdentToken, text: input[start:i], start: start, end: i})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(input) && (isDigitByte(input[i]) || input[i] == '_' ||
+				(input[i] == '.' && i+1 < len(input) && isDigitByte(input[i+1]))) {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: exprNumberToken, text: input[start:i], start: start, end: i})
+		case r == '\'' || r == '"' || r == '`':
+			start := i
+			i++
+			for ; i < len(input) && input[i] != byte(r); i++ {
+				if input[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(input) {
+				return nil, fmt.Errorf("`%v` has an unclosed string literal", input)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: exprStringToken, text: input[start:i], start: start, end: i})
+		default:
+			start := i
+			i += size
+			if i < len(input) {
+				switch input[start:i] + input[i:i+1] {
+				case "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "..", "=~", "!~", "**", "<<", ">>":
+					i++
+				}
+			}
+			tokens = append(tokens, exprToken{kind: exprPunctToken, text: input[start:i], start: start, end: i})
+		}
+	}
+	return tokens, nil
+}
+
+func isDigitByte(c byte) bool {
+	return c >= '0' && c <= '9'
+}
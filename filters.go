@@ -0,0 +1,498 @@
+package tplinator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type FilterFunc func(value interface{}, args ...interface{}) (interface{}, error)
+
+type FilterMap map[string]FilterFunc
+
+func DefaultFilters() FilterMap {
+	return FilterMap{
+		"upper":      upperFilter,
+		"lower":      lowerFilter,
+		"title":      titleFilter,
+		"capitalize": capitalizeFilter,
+		"trim":       trimFilter,
+		"truncate":   truncateFilter,
+		"default":    defaultFilter,
+		"join":       joinFilter,
+		"date":       dateFilter,
+		"number":     numberFilter,
+		"json":       jsonFilter,
+		"urlquery":   urlQueryFilter,
+		"pluralize":  pluralizeFilter,
+		"sortBy":     sortByFilter,
+		"limit":      limitFilter,
+	}
+}
+
+func mergeFilterMaps(filterMaps ...FilterMap) FilterMap {
+	merged := make(FilterMap)
+	for i := len(filterMaps) - 1; i >= 0; i-- {
+		for name, filter := range filterMaps[i] {
+			merged[name] = filter
+		}
+	}
+	return merged
+}
+
+func expectFilterArgs(args []interface{}, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("expects %d argument(s), got %d", min, len(args))
+		}
+		return fmt.Errorf("expects %d to %d argument(s), got %d", min, max, len(args))
+	}
+	return nil
+}
+
+func stringFilter(transform func(string) string) FilterFunc {
+	return func(value interface{}, args ...interface{}) (interface{}, error) {
+		if err := expectFilterArgs(args, 0, 0); err != nil {
+			return nil, err
+		}
+		str, err := FormatValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return transform(str), nil
+	}
+}
+
+var (
+	upperFilter = stringFilter(strings.ToUpper)
+	lowerFilter = stringFilter(strings.ToLower)
+	trimFilter  = stringFilter(strings.TrimSpace)
+
+	titleFilter = stringFilter(func(str string) string {
+		prev := ' '
+		return strings.Map(func(r rune) rune {
+			isWordStart := unicode.IsSpace(prev) || prev == '-'
+			prev = r
+			if isWordStart {
+				return unicode.ToTitle(r)
+			}
+			return r
+		}, str)
+	})
+	capitalizeFilter = stringFilter(func(str string) string {
+		r, size := utf8.DecodeRuneInString(str)
+		if r == utf8.RuneError {
+			return str
+		}
+		return string(unicode.ToUpper(r)) + str[size:]
+	})
+)
+
+func truncateFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	if err := expectFilterArgs(args, 1, 2); err != nil {
+		return nil, err
+	}
+	length, err := toInt(args[0])
+	if err != nil {
+		return nil, err
+	} else if length < 0 {
+		return nil, errors.New("length must not be negative")
+	}
+	ellipsis := "…"
+	if len(args) == 2 {
+		if ellipsis, err = FormatValue(args[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	str, err := FormatValue(value)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(str)
+	if len(runes) <= length {
+		return str, nil
+	}
+	return string(runes[:length]) + ellipsis, nil
+}
+
+func defaultFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	if err := expectFilterArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	if isZeroValue(value) {
+		return args[0], nil
+	}
+	return value, nil
+}
+
+func joinFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	if err := expectFilterArgs(args, 0, 1); err != nil {
+		return nil, err
+	}
+	separator := ", "
+	if len(args) == 1 {
+		var err error
+		if separator, err = FormatValue(args[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	items, err := sliceValue(value)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, items.Len())
+	for i := range strs {
+		if strs[i], err = FormatValue(items.Index(i).Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return strings.Join(strs, separator), nil
+}
+
+func dateFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	if err := expectFilterArgs(args, 0, 1); err != nil {
+		return nil, err
+	}
+	layout := time.RFC3339
+	if len(args) == 1 {
+		var err error
+		if layout, err = FormatValue(args[0]); err != nil {
+			return nil, err
+		}
+	}
+	if namedLayout, isNamed := namedDateLayouts[layout]; isNamed {
+		layout = namedLayout
+	}
+	t, err := toTime(value)
+	if err != nil {
+		return nil, err
+	}
+	return t.Format(layout), nil
+}
+
+// the govaluate evaluator turns string literals that look like a time into
+// a time.Time, so layouts such as `2006-01-02` can only be passed to its
+// date filter using one of these names.
+var namedDateLayouts = map[string]string{
+	"date":     "2006-01-02",
+	"datetime": "2006-01-02 15:04:05",
+	"time":     "15:04:05",
+	"kitchen":  time.Kitchen,
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+	"rfc822":   time.RFC822,
+}
+
+func numberFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	if err := expectFilterArgs(args, 0, 1); err != nil {
+		return nil, err
+	}
+	decimals := -1
+	if len(args) == 1 {
+		var err error
+		if decimals, err = toInt(args[0]); err != nil {
+			return nil, err
+		}
+	}
+	number, err := toFloat64(value)
+	if err != nil {
+		return nil, err
+	}
+	return formatGroupedNumber(number, decimals, ".", ","), nil
+}
+
+func formatGroupedNumber(number float64, decimals int, decimalSep, groupSep string) string {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+
+	str := strconv.FormatFloat(math.Abs(number), 'f', decimals, 64)
+	intPart, fracPart := str, ""
+	if dotIdx := strings.IndexByte(str, '.'); dotIdx >= 0 {
+		intPart, fracPart = str[:dotIdx], str[dotIdx+1:]
+	}
+
+	var sb strings.Builder
+	if number < 0 && strings.Trim(str, "0.") != "" {
+		sb.WriteByte('-')
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteString(groupSep)
+		}
+		sb.WriteRune(digit)
+	}
+	if fracPart != "" {
+		sb.WriteString(decimalSep)
+		sb.WriteString(fracPart)
+	}
+	return sb.String()
+}
+
+func jsonFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	if err := expectFilterArgs(args, 0, 0); err != nil {
+		return nil, err
+	}
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(jsonBytes), nil
+}
+
+func urlQueryFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	if err := expectFilterArgs(args, 0, 0); err != nil {
+		return nil, err
+	}
+	str, err := FormatValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return url.QueryEscape(str), nil
+}
+
+func pluralizeFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	if err := expectFilterArgs(args, 0, 2); err != nil {
+		return nil, err
+	}
+	count, err := toFloat64(value)
+	if err != nil {
+		return nil, err
+	}
+
+	singular, plural := "", "s"
+	switch len(args) {
+	case 1:
+		if plural, err = FormatValue(args[0]); err != nil {
+			return nil, err
+		}
+	case 2:
+		if singular, err = FormatValue(args[0]); err != nil {
+			return nil, err
+		}
+		if plural, err = FormatValue(args[1]); err != nil {
+			return nil, err
+		}
+	}
+	if count == 1 {
+		return singular, nil
+	}
+	return plural, nil
+}
+
+func sortByFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	field, descending, err := sortByArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	items, err := sliceValue(value)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, items.Len())
+	for i := range keys {
+		if keys[i], err = lookupField(items.Index(i).Interface(), field); err != nil {
+			return nil, err
+		}
+	}
+	indexes, err := sortPermutation(keys, descending)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := reflect.MakeSlice(items.Type(), items.Len(), items.Len())
+	for i, index := range indexes {
+		sorted.Index(i).Set(items.Index(index))
+	}
+	return sorted.Interface(), nil
+}
+
+// sortByArgs returns the field and whether the order is descending from
+// the arguments of `sortBy`, which is both a filter and a range modifier.
+func sortByArgs(args []interface{}) (string, bool, error) {
+	if err := expectFilterArgs(args, 1, 2); err != nil {
+		return "", false, err
+	}
+	field, err := FormatValue(args[0])
+	if err != nil {
+		return "", false, err
+	}
+	if len(args) == 1 {
+		return field, false, nil
+	}
+	order, err := FormatValue(args[1])
+	if err != nil {
+		return "", false, err
+	}
+	switch order {
+	case "asc":
+		return field, false, nil
+	case "desc":
+		return field, true, nil
+	default:
+		return "", false, fmt.Errorf("unknown sort order `%v`", order)
+	}
+}
+
+// sortPermutation returns the indexes of the keys in the order that
+// sorts them. Equal keys keep their order.
+func sortPermutation(keys []interface{}, descending bool) ([]int, error) {
+	indexes := make([]int, len(keys))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	var compareErr error
+	sort.SliceStable(indexes, func(i, j int) bool {
+		cmp, err := compareValues(keys[indexes[i]], keys[indexes[j]])
+		if err != nil && compareErr == nil {
+			compareErr = err
+		}
+		if descending {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	if compareErr != nil {
+		return nil, compareErr
+	}
+	return indexes, nil
+}
+
+func limitFilter(value interface{}, args ...interface{}) (interface{}, error) {
+	if err := expectFilterArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	limit, err := toInt(args[0])
+	if err != nil {
+		return nil, err
+	} else if limit < 0 {
+		return nil, errors.New("limit must not be negative")
+	}
+	items, err := sliceValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if limit > items.Len() {
+		limit = items.Len()
+	}
+	return items.Slice3(0, limit, limit).Interface(), nil
+}
+
+func sliceValue(value interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice:
+		return rv, nil
+	case reflect.Array:
+		slice := reflect.MakeSlice(reflect.SliceOf(rv.Type().Elem()), rv.Len(), rv.Len())
+		reflect.Copy(slice, rv)
+		return slice, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("expects a slice or an array, got %T", value)
+	}
+}
+
+func lookupField(item interface{}, field string) (interface{}, error) {
+	return resolvePath(item, strings.Split(field, "."))
+}
+
+func compareValues(a, b interface{}) (int, error) {
+	if aFloat, err := toFloat64(a); err == nil {
+		if bFloat, err := toFloat64(b); err == nil {
+			switch {
+			case aFloat < bFloat:
+				return -1, nil
+			case aFloat > bFloat:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	}
+	if aTime, isTime := a.(time.Time); isTime {
+		if bTime, isTime := b.(time.Time); isTime {
+			switch {
+			case aTime.Before(bTime):
+				return -1, nil
+			case aTime.After(bTime):
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	}
+	if aStr, isString := a.(string); isString {
+		if bStr, isString := b.(string); isString {
+			return strings.Compare(aStr, bStr), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %T with %T", a, b)
+}
+
+func isZeroValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return rv.IsZero()
+	}
+}
+
+func toInt(value interface{}) (int, error) {
+	number, err := toFloat64(value)
+	if err != nil {
+		return 0, err
+	} else if number != math.Trunc(number) {
+		return 0, fmt.Errorf("expects an integer, got %v", number)
+	}
+	return int(number), nil
+}
+
+func toFloat64(value interface{}) (float64, error) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	default:
+		return 0, fmt.Errorf("expects a number, got %T", value)
+	}
+}
+
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v != nil {
+			return *v, nil
+		}
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("`%v` is not an RFC 3339 time", v)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("expects a time, got %T", value)
+}
@@ -0,0 +1,212 @@
+package tplinator_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+func TestDefaultFilters(t *testing.T) {
+	filters := tplinator.DefaultFilters()
+	moment := time.Date(2018, time.September, 2, 13, 4, 5, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		filter string
+		value  interface{}
+		args   []interface{}
+
+		expected    interface{}
+		expectError bool
+	}{
+		{name: "upper", filter: "upper", value: "halfnoise", expected: "HALFNOISE"},
+		{name: "lower", filter: "lower", value: "HalfNoise", expected: "halfnoise"},
+		{name: "title", filter: "title", value: "the big brown-fox", expected: "The Big Brown-Fox"},
+		{name: "capitalize", filter: "capitalize", value: "someday soon", expected: "Someday soon"},
+		{name: "trim", filter: "trim", value: "  padded \n", expected: "padded"},
+		{name: "upper with args", filter: "upper", value: "a", args: []interface{}{1.0}, expectError: true},
+		{name: "truncate", filter: "truncate", value: "Blueberry Cheesecake", args: []interface{}{9.0}, expected: "Blueberry…"},
+		{name: "truncate with suffix", filter: "truncate", value: "Blueberry Cheesecake", args: []interface{}{9.0, "..."}, expected: "Blueberry..."},
+		{name: "truncate short", filter: "truncate", value: "Tea", args: []interface{}{9.0}, expected: "Tea"},
+		{name: "truncate non-integer", filter: "truncate", value: "Tea", args: []interface{}{1.5}, expectError: true},
+		{name: "default on empty", filter: "default", value: "", args: []interface{}{"n/a"}, expected: "n/a"},
+		{name: "default on nil", filter: "default", value: nil, args: []interface{}{"n/a"}, expected: "n/a"},
+		{name: "default on value", filter: "default", value: "Larry", args: []interface{}{"n/a"}, expected: "Larry"},
+		{name: "join", filter: "join", value: []string{"a", "b"}, expected: "a, b"},
+		{name: "join with separator", filter: "join", value: []interface{}{1.0, 2.0}, args: []interface{}{"/"}, expected: "1/2"},
+		{name: "join non-slice", filter: "join", value: "ab", expectError: true},
+		{name: "date", filter: "date", value: moment, expected: "2018-09-02T13:04:05Z"},
+		{name: "date named layout", filter: "date", value: moment, args: []interface{}{"date"}, expected: "2018-09-02"},
+		{name: "date layout", filter: "date", value: moment, args: []interface{}{"Jan 2"}, expected: "Sep 2"},
+		{name: "date from string", filter: "date", value: "2018-09-02T13:04:05Z", args: []interface{}{"kitchen"}, expected: "1:04PM"},
+		{name: "date of number", filter: "date", value: 1, expectError: true},
+		{name: "number", filter: "number", value: 1234567.5, expected: "1,234,567.5"},
+		{name: "number with decimals", filter: "number", value: -1234, args: []interface{}{2.0}, expected: "-1,234.00"},
+		{name: "number of string", filter: "number", value: "12", expectError: true},
+		{name: "json", filter: "json", value: map[string]interface{}{"a": []int{1}}, expected: `{"a":[1]}`},
+		{name: "urlquery", filter: "urlquery", value: "a b&c", expected: "a+b%26c"},
+		{name: "pluralize one", filter: "pluralize", value: 1, expected: ""},
+		{name: "pluralize many", filter: "pluralize", value: 2.0, expected: "s"},
+		{name: "pluralize suffix", filter: "pluralize", value: 0, args: []interface{}{"es"}, expected: "es"},
+		{name: "pluralize words", filter: "pluralize", value: 1, args: []interface{}{"child", "children"}, expected: "child"},
+		{
+			name: "sortBy", filter: "sortBy",
+			value: []map[string]interface{}{{"n": 2.0}, {"n": 1.0}, {"n": 3.0}},
+			args:  []interface{}{"n"},
+
+			expected: []map[string]interface{}{{"n": 1.0}, {"n": 2.0}, {"n": 3.0}},
+		},
+		{
+			name: "sortBy desc", filter: "sortBy",
+			value: tplinator.RangeParams(tplinator.EvaluatorParams{"n": "a"}, tplinator.EvaluatorParams{"n": "b"}),
+			args:  []interface{}{"n", "desc"},
+
+			expected: tplinator.RangeParams(tplinator.EvaluatorParams{"n": "b"}, tplinator.EvaluatorParams{"n": "a"}),
+		},
+		{
+			name: "sortBy missing field", filter: "sortBy",
+			value: []map[string]interface{}{{"n": 2.0}, {"m": 1.0}},
+			args:  []interface{}{"n"},
+
+			expectError: true,
+		},
+		{name: "limit", filter: "limit", value: []int{1, 2, 3}, args: []interface{}{2.0}, expected: []int{1, 2}},
+		{name: "limit over length", filter: "limit", value: [2]int{1, 2}, args: []interface{}{5.0}, expected: []int{1, 2}},
+		{name: "limit negative", filter: "limit", value: []int{1}, args: []interface{}{-1.0}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := filters[tc.filter](tc.value, tc.args...)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expecting an error, got `%v`", actual)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFilterPipeline(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		params   tplinator.EvaluatorParams
+
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "chained filters",
+			template: `<h1 title="{{go:name | lower}}">{{go: name | upper | truncate(4) }}</h1>`,
+			params:   tplinator.EvaluatorParams{"name": "Larry The Dog"},
+			expected: `<h1 title="larry the dog">LARR…</h1>`,
+		},
+		{
+			name:     "filter argument expression",
+			template: `<p>{{go:description | truncate(max - 1, '')}}</p>`,
+			params:   tplinator.EvaluatorParams{"description": "abcdef", "max": 4},
+			expected: `<p>abc</p>`,
+		},
+		{
+			name:     "logical or is not a pipe",
+			template: `<p go-if="hasOne || hasTwo">{{go:count}} item{{go:count | pluralize}}</p>`,
+			params:   tplinator.EvaluatorParams{"hasOne": false, "hasTwo": true, "count": 3},
+			expected: `<p>3 items</p>`,
+		},
+		{
+			name:     "pipe inside a string literal",
+			template: `<p>{{go:value | default('a | b')}}</p>`,
+			params:   tplinator.EvaluatorParams{"value": ""},
+			expected: `<p>a | b</p>`,
+		},
+		{
+			name:     "range source",
+			template: `<ul><li go-range="items | sortBy('n') | limit(2)">{{go:n}}</li></ul>`,
+			params: tplinator.EvaluatorParams{"items": tplinator.RangeParams(
+				tplinator.EvaluatorParams{"n": 3},
+				tplinator.EvaluatorParams{"n": 1},
+				tplinator.EvaluatorParams{"n": 2},
+			)},
+			expected: `<ul><li>1</li><li>2</li></ul>`,
+		},
+		{
+			name:        "unknown filter",
+			template:    `<p>{{go:name | shout}}</p>`,
+			params:      tplinator.EvaluatorParams{"name": "Larry"},
+			expectError: true,
+		},
+		{
+			name:        "invalid filter call",
+			template:    `<p>{{go:name | truncate(3}}</p>`,
+			params:      tplinator.EvaluatorParams{"name": "Larry"},
+			expectError: true,
+		},
+		{
+			name:        "missing expression",
+			template:    `<p>{{go: | upper}}</p>`,
+			params:      tplinator.EvaluatorParams{},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(strings.NewReader(tc.template))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			actual, err := tpl.RenderString(tc.params)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expecting an error, got `%v`", actual)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFiltersExtensionDependency(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<p>{{go:name | shout | upper}}</p>`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	tpl.AddExtensionDependencies(&filtersExtDep{
+		filters: tplinator.FilterMap{
+			"shout": func(value interface{}, args ...interface{}) (interface{}, error) {
+				return value.(string) + "!", nil
+			},
+		},
+	})
+
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{"name": "Larry"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<p>LARRY!</p>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+}
+
+type filtersExtDep struct {
+	filters tplinator.FilterMap
+}
+
+func (ed *filtersExtDep) Get(depKey tplinator.DependencyKey) interface{} {
+	if depKey == tplinator.FiltersExtDepKey {
+		return ed.filters
+	}
+	return nil
+}
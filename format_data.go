@@ -0,0 +1,268 @@
+package tplinator
+
+// localeFormats is the CLDR data that is needed to format numbers, money
+// and dates in a locale. It is bundled so that no data has to be loaded
+// at runtime.
+type localeFormats struct {
+	decimalSep string
+	groupSep   string
+	// minGrouping is the number of digits that the integer part of a
+	// number must have before it is grouped, e.g. 5 for `1234` to stay
+	// ungrouped in Spanish.
+	minGrouping int
+	// currencyPattern places the amount (`#`) and the symbol (`¤`)
+	currencyPattern string
+
+	dateStyles  map[string]string
+	months      [12]string
+	shortMonths [12]string
+	weekdays    [7]string
+	dayPeriods  [2]string
+
+	relativeNow  string
+	relativePast map[string]map[PluralCategory]string
+	relativeNext map[string]map[PluralCategory]string
+}
+
+func oneOther(one, other string) map[PluralCategory]string {
+	return map[PluralCategory]string{PluralOne: one, PluralOther: other}
+}
+
+func oneFewManyOther(one, few, many, other string) map[PluralCategory]string {
+	return map[PluralCategory]string{PluralOne: one, PluralFew: few, PluralMany: many, PluralOther: other}
+}
+
+func otherOnly(other string) map[PluralCategory]string {
+	return map[PluralCategory]string{PluralOther: other}
+}
+
+// defaultFormatsLocale is used for the locales without bundled data.
+const defaultFormatsLocale = "en"
+
+var bundledLocaleFormats = map[string]*localeFormats{
+	"en": {
+		decimalSep: ".", groupSep: ",", minGrouping: 4,
+		currencyPattern: "¤#",
+		dateStyles: map[string]string{
+			"short": "M/d/yy", "medium": "MMM d, y",
+			"long": "MMMM d, y", "full": "EEEE, MMMM d, y",
+		},
+		months: [12]string{"January", "February", "March", "April", "May", "June",
+			"July", "August", "September", "October", "November", "December"},
+		shortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun",
+			"Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday",
+			"Thursday", "Friday", "Saturday"},
+		dayPeriods:  [2]string{"AM", "PM"},
+		relativeNow: "now",
+		relativePast: map[string]map[PluralCategory]string{
+			"minute": oneOther("{0} minute ago", "{0} minutes ago"),
+			"hour":   oneOther("{0} hour ago", "{0} hours ago"),
+			"day":    oneOther("{0} day ago", "{0} days ago"),
+			"month":  oneOther("{0} month ago", "{0} months ago"),
+			"year":   oneOther("{0} year ago", "{0} years ago"),
+		},
+		relativeNext: map[string]map[PluralCategory]string{
+			"minute": oneOther("in {0} minute", "in {0} minutes"),
+			"hour":   oneOther("in {0} hour", "in {0} hours"),
+			"day":    oneOther("in {0} day", "in {0} days"),
+			"month":  oneOther("in {0} month", "in {0} months"),
+			"year":   oneOther("in {0} year", "in {0} years"),
+		},
+	},
+	"de": {
+		decimalSep: ",", groupSep: ".", minGrouping: 4,
+		currencyPattern: "#\u00a0¤",
+		dateStyles: map[string]string{
+			"short": "dd.MM.yy", "medium": "dd.MM.y",
+			"long": "d. MMMM y", "full": "EEEE, d. MMMM y",
+		},
+		months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni",
+			"Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni",
+			"Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch",
+			"Donnerstag", "Freitag", "Samstag"},
+		dayPeriods:  [2]string{"AM", "PM"},
+		relativeNow: "jetzt",
+		relativePast: map[string]map[PluralCategory]string{
+			"minute": oneOther("vor {0} Minute", "vor {0} Minuten"),
+			"hour":   oneOther("vor {0} Stunde", "vor {0} Stunden"),
+			"day":    oneOther("vor {0} Tag", "vor {0} Tagen"),
+			"month":  oneOther("vor {0} Monat", "vor {0} Monaten"),
+			"year":   oneOther("vor {0} Jahr", "vor {0} Jahren"),
+		},
+		relativeNext: map[string]map[PluralCategory]string{
+			"minute": oneOther("in {0} Minute", "in {0} Minuten"),
+			"hour":   oneOther("in {0} Stunde", "in {0} Stunden"),
+			"day":    oneOther("in {0} Tag", "in {0} Tagen"),
+			"month":  oneOther("in {0} Monat", "in {0} Monaten"),
+			"year":   oneOther("in {0} Jahr", "in {0} Jahren"),
+		},
+	},
+	"fr": {
+		decimalSep: ",", groupSep: "\u202f", minGrouping: 4,
+		currencyPattern: "#\u00a0¤",
+		dateStyles: map[string]string{
+			"short": "dd/MM/y", "medium": "d MMM y",
+			"long": "d MMMM y", "full": "EEEE d MMMM y",
+		},
+		months: [12]string{"janvier", "février", "mars", "avril", "mai", "juin",
+			"juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin",
+			"juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		weekdays: [7]string{"dimanche", "lundi", "mardi", "mercredi",
+			"jeudi", "vendredi", "samedi"},
+		dayPeriods:  [2]string{"AM", "PM"},
+		relativeNow: "maintenant",
+		relativePast: map[string]map[PluralCategory]string{
+			"minute": oneOther("il y a {0} minute", "il y a {0} minutes"),
+			"hour":   oneOther("il y a {0} heure", "il y a {0} heures"),
+			"day":    oneOther("il y a {0} jour", "il y a {0} jours"),
+			"month":  oneOther("il y a {0} mois", "il y a {0} mois"),
+			"year":   oneOther("il y a {0} an", "il y a {0} ans"),
+		},
+		relativeNext: map[string]map[PluralCategory]string{
+			"minute": oneOther("dans {0} minute", "dans {0} minutes"),
+			"hour":   oneOther("dans {0} heure", "dans {0} heures"),
+			"day":    oneOther("dans {0} jour", "dans {0} jours"),
+			"month":  oneOther("dans {0} mois", "dans {0} mois"),
+			"year":   oneOther("dans {0} an", "dans {0} ans"),
+		},
+	},
+	"es": {
+		decimalSep: ",", groupSep: ".", minGrouping: 5,
+		currencyPattern: "#\u00a0¤",
+		dateStyles: map[string]string{
+			"short": "d/M/yy", "medium": "d MMM y",
+			"long": "d 'de' MMMM 'de' y", "full": "EEEE, d 'de' MMMM 'de' y",
+		},
+		months: [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio",
+			"julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun",
+			"jul", "ago", "sept", "oct", "nov", "dic"},
+		weekdays: [7]string{"domingo", "lunes", "martes", "miércoles",
+			"jueves", "viernes", "sábado"},
+		dayPeriods:  [2]string{"a.\u00a0m.", "p.\u00a0m."},
+		relativeNow: "ahora",
+		relativePast: map[string]map[PluralCategory]string{
+			"minute": oneOther("hace {0} minuto", "hace {0} minutos"),
+			"hour":   oneOther("hace {0} hora", "hace {0} horas"),
+			"day":    oneOther("hace {0} día", "hace {0} días"),
+			"month":  oneOther("hace {0} mes", "hace {0} meses"),
+			"year":   oneOther("hace {0} año", "hace {0} años"),
+		},
+		relativeNext: map[string]map[PluralCategory]string{
+			"minute": oneOther("dentro de {0} minuto", "dentro de {0} minutos"),
+			"hour":   oneOther("dentro de {0} hora", "dentro de {0} horas"),
+			"day":    oneOther("dentro de {0} día", "dentro de {0} días"),
+			"month":  oneOther("dentro de {0} mes", "dentro de {0} meses"),
+			"year":   oneOther("dentro de {0} año", "dentro de {0} años"),
+		},
+	},
+	"pt": {
+		decimalSep: ",", groupSep: ".", minGrouping: 4,
+		currencyPattern: "¤\u00a0#",
+		dateStyles: map[string]string{
+			"short": "dd/MM/y", "medium": "d 'de' MMM 'de' y",
+			"long": "d 'de' MMMM 'de' y", "full": "EEEE, d 'de' MMMM 'de' y",
+		},
+		months: [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho",
+			"julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		shortMonths: [12]string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.",
+			"jul.", "ago.", "set.", "out.", "nov.", "dez."},
+		weekdays: [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira",
+			"quinta-feira", "sexta-feira", "sábado"},
+		dayPeriods:  [2]string{"AM", "PM"},
+		relativeNow: "agora",
+		relativePast: map[string]map[PluralCategory]string{
+			"minute": oneOther("há {0} minuto", "há {0} minutos"),
+			"hour":   oneOther("há {0} hora", "há {0} horas"),
+			"day":    oneOther("há {0} dia", "há {0} dias"),
+			"month":  oneOther("há {0} mês", "há {0} meses"),
+			"year":   oneOther("há {0} ano", "há {0} anos"),
+		},
+		relativeNext: map[string]map[PluralCategory]string{
+			"minute": oneOther("em {0} minuto", "em {0} minutos"),
+			"hour":   oneOther("em {0} hora", "em {0} horas"),
+			"day":    oneOther("em {0} dia", "em {0} dias"),
+			"month":  oneOther("em {0} mês", "em {0} meses"),
+			"year":   oneOther("em {0} ano", "em {0} anos"),
+		},
+	},
+	"ru": {
+		decimalSep: ",", groupSep: "\u00a0", minGrouping: 4,
+		currencyPattern: "#\u00a0¤",
+		dateStyles: map[string]string{
+			"short": "dd.MM.y", "medium": "d MMM y 'г'.",
+			"long": "d MMMM y 'г'.", "full": "EEEE, d MMMM y 'г'.",
+		},
+		months: [12]string{"января", "февраля", "марта", "апреля", "мая", "июня",
+			"июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		shortMonths: [12]string{"янв.", "февр.", "мар.", "апр.", "мая", "июн.",
+			"июл.", "авг.", "сент.", "окт.", "нояб.", "дек."},
+		weekdays: [7]string{"воскресенье", "понедельник", "вторник", "среда",
+			"четверг", "пятница", "суббота"},
+		dayPeriods:  [2]string{"AM", "PM"},
+		relativeNow: "сейчас",
+		relativePast: map[string]map[PluralCategory]string{
+			"minute": oneFewManyOther("{0} минуту назад", "{0} минуты назад", "{0} минут назад", "{0} минуты назад"),
+			"hour":   oneFewManyOther("{0} час назад", "{0} часа назад", "{0} часов назад", "{0} часа назад"),
+			"day":    oneFewManyOther("{0} день назад", "{0} дня назад", "{0} дней назад", "{0} дня назад"),
+			"month":  oneFewManyOther("{0} месяц назад", "{0} месяца назад", "{0} месяцев назад", "{0} месяца назад"),
+			"year":   oneFewManyOther("{0} год назад", "{0} года назад", "{0} лет назад", "{0} года назад"),
+		},
+		relativeNext: map[string]map[PluralCategory]string{
+			"minute": oneFewManyOther("через {0} минуту", "через {0} минуты", "через {0} минут", "через {0} минуты"),
+			"hour":   oneFewManyOther("через {0} час", "через {0} часа", "через {0} часов", "через {0} часа"),
+			"day":    oneFewManyOther("через {0} день", "через {0} дня", "через {0} дней", "через {0} дня"),
+			"month":  oneFewManyOther("через {0} месяц", "через {0} месяца", "через {0} месяцев", "через {0} месяца"),
+			"year":   oneFewManyOther("через {0} год", "через {0} года", "через {0} лет", "через {0} года"),
+		},
+	},
+	"ja": {
+		decimalSep: ".", groupSep: ",", minGrouping: 4,
+		currencyPattern: "¤#",
+		dateStyles: map[string]string{
+			"short": "y/MM/dd", "medium": "y/MM/dd",
+			"long": "y年M月d日", "full": "y年M月d日EEEE",
+		},
+		months: [12]string{"1月", "2月", "3月", "4月", "5月", "6月",
+			"7月", "8月", "9月", "10月", "11月", "12月"},
+		shortMonths: [12]string{"1月", "2月", "3月", "4月", "5月", "6月",
+			"7月", "8月", "9月", "10月", "11月", "12月"},
+		weekdays: [7]string{"日曜日", "月曜日", "火曜日", "水曜日",
+			"木曜日", "金曜日", "土曜日"},
+		dayPeriods:  [2]string{"午前", "午後"},
+		relativeNow: "今",
+		relativePast: map[string]map[PluralCategory]string{
+			"minute": otherOnly("{0} 分前"),
+			"hour":   otherOnly("{0} 時間前"),
+			"day":    otherOnly("{0} 日前"),
+			"month":  otherOnly("{0} か月前"),
+			"year":   otherOnly("{0} 年前"),
+		},
+		relativeNext: map[string]map[PluralCategory]string{
+			"minute": otherOnly("{0} 分後"),
+			"hour":   otherOnly("{0} 時間後"),
+			"day":    otherOnly("{0} 日後"),
+			"month":  otherOnly("{0} か月後"),
+			"year":   otherOnly("{0} 年後"),
+		},
+	},
+}
+
+// currencySymbols has the symbols of the common currencies. The other
+// currencies are written using their code.
+var currencySymbols = map[string]string{
+	"USD": "$", "EUR": "€", "GBP": "£", "JPY": "¥", "CNY": "CN¥",
+	"INR": "₹", "KRW": "₩", "BRL": "R$", "RUB": "₽", "PLN": "zł",
+	"CAD": "CA$", "AUD": "A$", "MXN": "MX$", "CHF": "CHF",
+}
+
+// currencyDigits has the number of fraction digits of the currencies
+// which do not use 2.
+var currencyDigits = map[string]int{
+	"JPY": 0, "KRW": 0, "CLP": 0, "ISK": 0, "VND": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+}
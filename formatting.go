@@ -0,0 +1,272 @@
+package tplinator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localeFormatter has the built-in formatting functions of a locale.
+type localeFormatter struct {
+	locale  string
+	formats *localeFormats
+}
+
+func newLocaleFormatter(locale string) localeFormatter {
+	normalized := normalizeLocale(locale)
+	formats, hasFormats := bundledLocaleFormats[normalized]
+	if !hasFormats {
+		formats, hasFormats = bundledLocaleFormats[localeLanguage(normalized)]
+	}
+	if !hasFormats {
+		formats = bundledLocaleFormats[defaultFormatsLocale]
+	}
+	return localeFormatter{locale: locale, formats: formats}
+}
+
+func (lf localeFormatter) funcs() FuncMap {
+	return FuncMap{
+		"formatNumber":   lf.formatNumber,
+		"formatCurrency": lf.formatCurrency,
+		"formatDate":     lf.formatDate,
+		"relativeTime":   lf.relativeTime,
+	}
+}
+
+// localeFuncs returns the built-in functions that depend on the locale of
+// the node's scope. The error of the last call to `t` is kept in
+// translateErr.
+func localeFuncs(
+	node *Node, dependencies ExtensionDependencies,
+	scope scopeChain, translateErr *error,
+) FuncMap {
+	funcs := newLocaleFormatter(renderLocale(dependencies, scope)).funcs()
+	if _, hasCatalog := dependencies.Get(CatalogExtDepKey).(Catalog); hasCatalog {
+		funcs["t"] = translateFunc(node, dependencies, scope, translateErr)
+	}
+	return funcs
+}
+
+// formatNumber groups the digits of the number. The number of fraction
+// digits can be given, otherwise floats have up to 3 of them.
+func (lf localeFormatter) formatNumber(value interface{}, decimals ...int) (string, error) {
+	n, isNumber := toNumber(value)
+	if !isNumber {
+		return "", fmt.Errorf("expecting a number, got %T", value)
+	}
+	minFraction, maxFraction := 0, 3
+	if len(decimals) > 0 {
+		if decimals[0] < 0 {
+			return "", fmt.Errorf("expecting a non-negative number of decimals, got %d", decimals[0])
+		}
+		minFraction, maxFraction = decimals[0], decimals[0]
+	}
+	return lf.formatDecimal(n, minFraction, maxFraction), nil
+}
+
+// formatCurrency formats the amount using the symbol and the number of
+// fraction digits of the ISO 4217 currency code.
+func (lf localeFormatter) formatCurrency(amount interface{}, currency string) (string, error) {
+	n, isNumber := toNumber(amount)
+	if !isNumber {
+		return "", fmt.Errorf("expecting a number, got %T", amount)
+	}
+	if len(currency) != 3 || strings.ToUpper(currency) != currency {
+		return "", fmt.Errorf("`%v` is not a currency code", currency)
+	}
+	digits, hasDigits := currencyDigits[currency]
+	if !hasDigits {
+		digits = 2
+	}
+	symbol, hasSymbol := currencySymbols[currency]
+	if !hasSymbol {
+		symbol = currency
+	}
+
+	formatted := lf.formatDecimal(n, digits, digits)
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	pattern := strings.Replace(lf.formats.currencyPattern, "#", formatted, 1)
+	return sign + strings.Replace(pattern, "¤", symbol, 1), nil
+}
+
+func (lf localeFormatter) formatDecimal(n number, minFraction, maxFraction int) string {
+	var digits string
+	if n.isInt && minFraction == 0 {
+		digits = strconv.Itoa(n.intValue)
+	} else {
+		digits = strconv.FormatFloat(n.float(), 'f', maxFraction, 64)
+	}
+
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+	intPart, fraction := digits, ""
+	if dotIdx := strings.IndexByte(digits, '.'); dotIdx >= 0 {
+		intPart, fraction = digits[:dotIdx], digits[dotIdx+1:]
+	}
+	for len(fraction) > minFraction && strings.HasSuffix(fraction, "0") {
+		fraction = fraction[:len(fraction)-1]
+	}
+	if negative && strings.Trim(intPart+fraction, "0") == "" {
+		negative = false
+	}
+
+	var sb strings.Builder
+	if negative {
+		sb.WriteByte('-')
+	}
+	if len(intPart) >= lf.formats.minGrouping {
+		for digitIdx, digit := range intPart {
+			if digitIdx > 0 && (len(intPart)-digitIdx)%3 == 0 {
+				sb.WriteString(lf.formats.groupSep)
+			}
+			sb.WriteRune(digit)
+		}
+	} else {
+		sb.WriteString(intPart)
+	}
+	if fraction != "" {
+		sb.WriteString(lf.formats.decimalSep)
+		sb.WriteString(fraction)
+	}
+	return sb.String()
+}
+
+// formatDate formats the time using one of the `short`, `medium` (the
+// default), `long` and `full` styles of the locale, or using a CLDR date
+// pattern like `d MMM y, HH:mm`.
+func (lf localeFormatter) formatDate(t time.Time, style ...string) (string, error) {
+	pattern := lf.formats.dateStyles["medium"]
+	if len(style) > 0 {
+		if stylePattern, isStyle := lf.formats.dateStyles[style[0]]; isStyle {
+			pattern = stylePattern
+		} else {
+			pattern = style[0]
+		}
+	}
+	return lf.formatDatePattern(t, pattern), nil
+}
+
+func (lf localeFormatter) formatDatePattern(t time.Time, pattern string) string {
+	var sb strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\'':
+			// a quoted literal, or a quote if it is doubled
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == i+1 {
+				sb.WriteRune('\'')
+			} else {
+				sb.WriteString(string(runes[i+1 : end]))
+			}
+			i = end + 1
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			width := 1
+			for i+width < len(runes) && runes[i+width] == r {
+				width++
+			}
+			sb.WriteString(lf.dateField(t, r, width))
+			i += width
+		default:
+			sb.WriteRune(r)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+func (lf localeFormatter) dateField(t time.Time, field rune, width int) string {
+	pad := func(value int) string {
+		if width >= 2 {
+			return fmt.Sprintf("%02d", value)
+		}
+		return strconv.Itoa(value)
+	}
+
+	switch field {
+	case 'y':
+		if width == 2 {
+			return fmt.Sprintf("%02d", t.Year()%100)
+		}
+		return strconv.Itoa(t.Year())
+	case 'M', 'L':
+		switch {
+		case width >= 4:
+			return lf.formats.months[t.Month()-1]
+		case width == 3:
+			return lf.formats.shortMonths[t.Month()-1]
+		default:
+			return pad(int(t.Month()))
+		}
+	case 'd':
+		return pad(t.Day())
+	case 'E':
+		return lf.formats.weekdays[t.Weekday()]
+	case 'H':
+		return pad(t.Hour())
+	case 'h':
+		hour := t.Hour() % 12
+		if hour == 0 {
+			hour = 12
+		}
+		return pad(hour)
+	case 'm':
+		return pad(t.Minute())
+	case 's':
+		return pad(t.Second())
+	case 'a':
+		return lf.formats.dayPeriods[t.Hour()/12]
+	default:
+		return strings.Repeat(string(field), width)
+	}
+}
+
+// relativeTime describes the time relative to now (e.g. `3 days ago` or
+// `in 2 hours`) using the largest unit that fits. The time that is
+// considered now can be given.
+func (lf localeFormatter) relativeTime(t time.Time, now ...time.Time) (string, error) {
+	reference := time.Now()
+	if len(now) > 0 {
+		reference = now[0]
+	}
+
+	diff := t.Sub(reference)
+	phrases := lf.formats.relativeNext
+	if diff < 0 {
+		diff, phrases = -diff, lf.formats.relativePast
+	}
+
+	days := math.Round(diff.Hours() / 24)
+	var unit string
+	var count int
+	switch minutes, hours := math.Round(diff.Minutes()), math.Round(diff.Hours()); {
+	case diff < time.Minute:
+		return lf.formats.relativeNow, nil
+	case minutes < 60:
+		unit, count = "minute", int(minutes)
+	case hours < 24:
+		unit, count = "hour", int(hours)
+	case days < 30:
+		unit, count = "day", int(days)
+	case math.Round(days/30.44) < 12:
+		unit, count = "month", int(math.Round(days/30.44))
+	default:
+		unit, count = "year", int(math.Max(1, math.Round(days/365.25)))
+	}
+
+	forms := phrases[unit]
+	phrase, hasPhrase := forms[pluralCategoryOf(lf.locale, count)]
+	if !hasPhrase {
+		phrase = forms[PluralOther]
+	}
+	return strings.Replace(phrase, "{0}", lf.formatDecimal(number{isInt: true, intValue: count}, 0, 0), 1), nil
+}
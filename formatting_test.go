@@ -0,0 +1,119 @@
+package tplinator_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+func TestFormattingFuncs(t *testing.T) {
+	date := time.Date(2021, time.March, 7, 15, 4, 5, 0, time.UTC)
+	params := tplinator.EvaluatorParams{
+		"date":  date,
+		"then":  date.Add(-26 * time.Hour),
+		"later": date.Add(3 * 24 * time.Hour),
+		"now":   date,
+	}
+	evaluator := tplinator.NewNativeEvaluator()
+
+	testCases := []struct {
+		locale string
+		input  string
+
+		expected      string
+		expectedError string
+	}{
+		{locale: "en", input: "formatNumber(1234567)", expected: "1,234,567"},
+		{locale: "en", input: "formatNumber(-1234.5678)", expected: "-1,234.568"},
+		{locale: "en", input: "formatNumber(1234.5, 2)", expected: "1,234.50"},
+		{locale: "en", input: "formatNumber(-0.0001)", expected: "0"},
+		{locale: "de-DE", input: "formatNumber(1234567.25)", expected: "1.234.567,25"},
+		{locale: "fr", input: "formatNumber(1234.5)", expected: "1\u202f234,5"},
+		{locale: "es", input: "formatNumber(1234)", expected: "1234"},
+		{locale: "es", input: "formatNumber(12345)", expected: "12.345"},
+		{locale: "xx", input: "formatNumber(1234)", expected: "1,234"},
+		{locale: "en", input: "formatNumber('12')", expectedError: "expecting a number"},
+
+		{locale: "en", input: "formatCurrency(1234.5, 'USD')", expected: "$1,234.50"},
+		{locale: "en", input: "formatCurrency(-3, 'EUR')", expected: "-€3.00"},
+		{locale: "de", input: "formatCurrency(1234.5, 'EUR')", expected: "1.234,50\u00a0€"},
+		{locale: "pt-BR", input: "formatCurrency(10, 'BRL')", expected: "R$\u00a010,00"},
+		{locale: "ja", input: "formatCurrency(1234.5, 'JPY')", expected: "¥1,234"},
+		{locale: "en", input: "formatCurrency(1, 'SEK')", expected: "SEK1.00"},
+		{locale: "en", input: "formatCurrency(1, 'euro')", expectedError: "`euro` is not a currency code"},
+
+		{locale: "en", input: "formatDate(date)", expected: "Mar 7, 2021"},
+		{locale: "en", input: "formatDate(date, 'short')", expected: "3/7/21"},
+		{locale: "en", input: "formatDate(date, 'full')", expected: "Sunday, March 7, 2021"},
+		{locale: "en", input: "formatDate(date, 'h:mm a')", expected: "3:04 PM"},
+		{locale: "de", input: "formatDate(date, 'long')", expected: "7. März 2021"},
+		{locale: "es", input: "formatDate(date, 'long')", expected: "7 de marzo de 2021"},
+		{locale: "ru", input: "formatDate(date, 'full')", expected: "воскресенье, 7 марта 2021 г."},
+		{locale: "ja", input: "formatDate(date, 'long')", expected: "2021年3月7日"},
+		{locale: "fr", input: "formatDate(date, \"d MMM y 'à' HH:mm\")", expected: "7 mars 2021 à 15:04"},
+
+		{locale: "en", input: "relativeTime(then, now)", expected: "1 day ago"},
+		{locale: "en", input: "relativeTime(later, now)", expected: "in 3 days"},
+		{locale: "en", input: "relativeTime(now, now)", expected: "now"},
+		{locale: "de", input: "relativeTime(later, now)", expected: "in 3 Tagen"},
+		{locale: "ru", input: "relativeTime(later, now)", expected: "через 3 дня"},
+		{locale: "fr", input: "relativeTime(then, now)", expected: "il y a 1 jour"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.locale+" "+tc.input, func(t *testing.T) {
+			params["locale"] = tc.locale
+			actual, err := evaluator.Evaluate(tc.input, params)
+			if tc.expectedError != "" {
+				if err == nil {
+					t.Errorf("expecting an error, got `%v`", actual)
+				} else if !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("wanted an error containing `%v`, got `%v`", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
+
+type invoicePage struct {
+	Total   float64
+	DueDate time.Time
+}
+
+func TestFormattingFuncs_Template(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<p title="{{go:formatDate(DueDate, 'short')}}">` +
+			`{{go:formatCurrency(Total, 'EUR')}} ({{go:formatNumber(Total)}})</p>`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tplinator.Check(tpl, reflect.TypeOf(invoicePage{})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	page := invoicePage{Total: 1999.5, DueDate: time.Date(2021, time.March, 7, 0, 0, 0, 0, time.UTC)}
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{
+		"Total": page.Total, "DueDate": page.DueDate, "locale": "de",
+	})
+	if expected := "<p title=\"07.03.21\">1.999,50\u00a0€ (1.999,5)</p>"; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+
+	// without a locale, the formats of English are used
+	var sb strings.Builder
+	if err := tpl.RenderValue(&sb, page); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<p title="3/7/21">€1,999.50 (1,999.5)</p>`; sb.String() != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, sb.String())
+	}
+}
@@ -0,0 +1,167 @@
+package tplinator
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+type FuncMap map[string]interface{}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// builtinFuncs are available to every expression. The registered
+// functions take precedence over them.
+var builtinFuncs = FuncMap{
+	"range": rangeFunc,
+}
+
+// rangeFunc returns an iterator func over the ints from start up to but not
+// including stop, i.e. `range(stop)`, `range(start, stop)` or
+// `range(start, stop, step)`. A negative step counts down.
+func rangeFunc(bounds ...int) (func(yield func(int) bool), error) {
+	start, stop, step := 0, 0, 1
+	switch len(bounds) {
+	case 1:
+		stop = bounds[0]
+	case 2:
+		start, stop = bounds[0], bounds[1]
+	case 3:
+		start, stop, step = bounds[0], bounds[1], bounds[2]
+	default:
+		return nil, fmt.Errorf("range expects 1 to 3 arguments, got %d", len(bounds))
+	}
+	if step == 0 {
+		return nil, fmt.Errorf("range expects a non-zero step")
+	}
+	// a step that was given must count towards stop, while `range(1, count)`
+	// is empty for a count of zero like it is in Python
+	if len(bounds) == 3 && ((step > 0 && start > stop) || (step < 0 && start < stop)) {
+		return nil, fmt.Errorf("range cannot count from %d to %d using a step of %d", start, stop, step)
+	}
+	return intRange(start, stop, step), nil
+}
+
+func mergeFuncMaps(funcMaps ...FuncMap) FuncMap {
+	merged := make(FuncMap)
+	for i := len(funcMaps) - 1; i >= 0; i-- {
+		for name, fn := range funcMaps[i] {
+			merged[name] = fn
+		}
+	}
+	return merged
+}
+
+func validateFuncMap(funcs FuncMap) error {
+	for name, fn := range funcs {
+		if !isIdentifier(name) {
+			return fmt.Errorf("funcs: `%v` is not a valid function name", name)
+		}
+		if err := validateFunc(name, reflect.ValueOf(fn)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateFunc(name string, fn reflect.Value) error {
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return fmt.Errorf("funcs: `%v` is not a function", name)
+	}
+	fnType := fn.Type()
+	switch {
+	case fnType.NumOut() == 1:
+	case fnType.NumOut() == 2 && fnType.Out(1) == errorType:
+	default:
+		return fmt.Errorf("funcs: `%v` must return one value, "+
+			"or one value and an error", name)
+	}
+	return nil
+}
+
+// callFunc calls the function using the arguments produced by an
+// evaluator. Numbers are converted to the numeric type of the parameter
+// as long as the conversion does not lose information.
+func callFunc(name string, fn reflect.Value, args []interface{}) (interface{}, error) {
+	if err := validateFunc(name, fn); err != nil {
+		return nil, err
+	}
+
+	fnType := fn.Type()
+	numIn := fnType.NumIn()
+	if fnType.IsVariadic() {
+		if len(args) < numIn-1 {
+			return nil, fmt.Errorf("function `%v` expects at least %d argument(s), got %d",
+				name, numIn-1, len(args))
+		}
+	} else if len(args) != numIn {
+		return nil, fmt.Errorf("function `%v` expects %d argument(s), got %d",
+			name, numIn, len(args))
+	}
+
+	argValues := make([]reflect.Value, len(args))
+	for argIdx, arg := range args {
+		var paramType reflect.Type
+		if fnType.IsVariadic() && argIdx >= numIn-1 {
+			paramType = fnType.In(numIn - 1).Elem()
+		} else {
+			paramType = fnType.In(argIdx)
+		}
+
+		argValue, err := convertArg(arg, paramType)
+		if err != nil {
+			return nil, fmt.Errorf("function `%v`: argument %d: %v", name, argIdx+1, err)
+		}
+		argValues[argIdx] = argValue
+	}
+
+	results := fn.Call(argValues)
+	if len(results) == 2 && !results[1].IsNil() {
+		return nil, fmt.Errorf("function `%v`: %v", name, results[1].Interface())
+	}
+	return results[0].Interface(), nil
+}
+
+func convertArg(arg interface{}, paramType reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		switch paramType.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return reflect.Zero(paramType), nil
+		default:
+			return reflect.Value{}, fmt.Errorf("cannot use nil as %v", paramType)
+		}
+	}
+
+	argValue := reflect.ValueOf(arg)
+	argType := argValue.Type()
+	if argType.AssignableTo(paramType) {
+		return argValue, nil
+	}
+
+	if isNumberKind(argType.Kind()) && isNumberKind(paramType.Kind()) {
+		number, _ := toFloat64(arg)
+		converted := argValue.Convert(paramType)
+		convertedBack, _ := toFloat64(converted.Interface())
+		if convertedBack != number && !(math.IsNaN(number) && math.IsNaN(convertedBack)) {
+			return reflect.Value{}, fmt.Errorf("cannot use %v as %v without losing information",
+				number, paramType)
+		}
+		return converted, nil
+	}
+	if argType.Kind() == paramType.Kind() && argType.ConvertibleTo(paramType) {
+		return argValue.Convert(paramType), nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot use %v (type %v) as %v", arg, argType, paramType)
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,176 @@
+package tplinator_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+func TestFuncs(t *testing.T) {
+	funcs := tplinator.FuncMap{
+		"hasRole": func(roles []string, role string) bool {
+			for _, r := range roles {
+				if r == role {
+					return true
+				}
+			}
+			return false
+		},
+		"formatMoney": func(cents int) string {
+			return fmt.Sprintf("$%d.%02d", cents/100, cents%100)
+		},
+		"sum": func(numbers ...float64) float64 {
+			total := 0.0
+			for _, number := range numbers {
+				total += number
+			}
+			return total
+		},
+		"itemCount": func() int {
+			return 3
+		},
+		"fail": func() (string, error) {
+			return "", errors.New("out of stock")
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		params   tplinator.EvaluatorParams
+
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "conditional",
+			template: `<div><p go-if="hasRole(roles, 'admin')">Admin</p><p go-else>Guest</p></div>`,
+			params:   tplinator.EvaluatorParams{"roles": []string{"user", "admin"}},
+			expected: `<div><p>Admin</p></div>`,
+		},
+		{
+			name:     "conditional class",
+			template: `<p go-if-class-admin="hasRole(roles, 'admin')"></p>`,
+			params:   tplinator.EvaluatorParams{"roles": []string{"admin"}},
+			expected: `<p class="admin"></p>`,
+		},
+		{
+			name:     "interpolation",
+			template: `<p title="{{go:formatMoney(total)}}">{{go:formatMoney(total + 1)}}</p>`,
+			params:   tplinator.EvaluatorParams{"total": 1999},
+			expected: `<p title="$19.99">$20.00</p>`,
+		},
+		{
+			name:     "variadic and numeric results",
+			template: `<p go-if="itemCount() > 2">{{go:sum(1, 2, itemCount())}}</p>`,
+			params:   tplinator.EvaluatorParams{},
+			expected: `<p>6</p>`,
+		},
+		{
+			name:          "argument count mismatch",
+			template:      `<p>{{go:formatMoney()}}</p>`,
+			params:        tplinator.EvaluatorParams{},
+			expectedError: "function `formatMoney` expects 1 argument(s), got 0",
+		},
+		{
+			name:          "argument type mismatch",
+			template:      `<p>{{go:formatMoney('ten')}}</p>`,
+			params:        tplinator.EvaluatorParams{},
+			expectedError: "function `formatMoney`: argument 1: cannot use ten (type string) as int",
+		},
+		{
+			name:          "lossy number conversion",
+			template:      `<p>{{go:formatMoney(total)}}</p>`,
+			params:        tplinator.EvaluatorParams{"total": 19.5},
+			expectedError: "function `formatMoney`: argument 1: cannot use 19.5 as int without losing information",
+		},
+		{
+			name:          "function error",
+			template:      `<p>{{go:fail()}}</p>`,
+			params:        tplinator.EvaluatorParams{},
+			expectedError: "function `fail`: out of stock",
+		},
+		{
+			name:          "unknown function",
+			template:      `<p>{{go:shout(name)}}</p>`,
+			params:        tplinator.EvaluatorParams{"name": "Larry"},
+			expectedError: "unknown function `shout`",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(
+				strings.NewReader(tc.template),
+				tplinator.FuncsParserOption(funcs),
+			)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			actual, err := tpl.RenderString(tc.params)
+			if tc.expectedError != "" {
+				if err == nil {
+					t.Errorf("expecting an error, got `%v`", actual)
+				} else if !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("wanted an error containing `%v`, got `%v`", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTemplate_AddFuncs(t *testing.T) {
+	tpl, err := tplinator.Tplinate(
+		strings.NewReader(`<p>{{go:greet(name)}}</p>`),
+		tplinator.FuncsParserOption(tplinator.FuncMap{
+			"greet": func(name string) string { return "Hi, " + name },
+		}),
+	)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	err = tpl.AddFuncs(tplinator.FuncMap{
+		"greet": func(name string) string { return "Hello, " + name },
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{"name": "Larry"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<p>Hello, Larry</p>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+
+	err = tpl.AddFuncs(tplinator.FuncMap{"greet": "Hello"})
+	if err == nil {
+		t.Error("expecting an error because `greet` is not a function")
+	}
+	err = tpl.AddFuncs(tplinator.FuncMap{"greet": func() (string, string) { return "", "" }})
+	if err == nil {
+		t.Error("expecting an error because `greet` returns two values")
+	}
+	err = tpl.AddFuncs(tplinator.FuncMap{"say-hi": func() string { return "" }})
+	if err == nil {
+		t.Error("expecting an error because `say-hi` is not a valid function name")
+	}
+
+	_, err = tplinator.Tplinate(
+		strings.NewReader(`<p></p>`),
+		tplinator.FuncsParserOption(tplinator.FuncMap{"greet": nil}),
+	)
+	if err == nil {
+		t.Error("expecting an error because `greet` is not a function")
+	}
+}
@@ -1,5 +1,7 @@
 module github.com/bmdelacruz/tplinator
 
+go 1.17
+
 require (
 	github.com/Knetic/govaluate v3.0.0+incompatible
 	github.com/alediaferia/stackgo v1.1.1
@@ -0,0 +1,582 @@
+package tplinator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// LocaleParam is the name of the render param, or of a context param,
+// which has the locale that is used to look up the messages.
+const LocaleParam = "locale"
+
+// Catalog provides the translated messages of the templates. It is
+// looked up from the ExtensionDependencies using CatalogExtDepKey.
+type Catalog interface {
+	// Message returns the message of the key for the locale. The count
+	// selects the plural form of the message and it is nil if the
+	// message is not used with a count.
+	Message(locale, key string, count interface{}) (string, bool)
+}
+
+// DiagnosticFunc receives the problems that do not need to stop the
+// rendering (e.g. a *MissingMessageError). It is looked up from the
+// ExtensionDependencies using DiagnosticsExtDepKey.
+type DiagnosticFunc func(err error)
+
+type MissingMessageError struct {
+	Locale   string
+	Key      string
+	Position Position
+}
+
+func (e *MissingMessageError) Error() string {
+	msg := "missing message `" + e.Key + "`"
+	if e.Locale != "" {
+		msg += " for locale `" + e.Locale + "`"
+	}
+	if e.Position.IsValid() {
+		msg = e.Position.String() + ": " + msg
+	}
+	return msg
+}
+
+type catalogMessage struct {
+	// categories has the forms of the messages that came from JSON
+	categories map[PluralCategory]string
+	// forms has the forms of the messages that came from a .po file in
+	// the order of their plural index
+	forms []string
+}
+
+type catalogLocale struct {
+	messages      map[string]catalogMessage
+	pluralFormula string
+}
+
+// MessageCatalog is a Catalog whose messages are added one by one or
+// loaded from JSON or gettext .po files. The messages of a locale which
+// has a region (e.g. `pt-BR`) fall back to the messages of its language
+// and then to the messages of the default locale.
+type MessageCatalog struct {
+	defaultLocale string
+	locales       map[string]*catalogLocale
+}
+
+func NewMessageCatalog(defaultLocale string) *MessageCatalog {
+	return &MessageCatalog{
+		defaultLocale: defaultLocale,
+		locales:       make(map[string]*catalogLocale),
+	}
+}
+
+func normalizeLocale(locale string) string {
+	if dotIdx := strings.IndexByte(locale, '.'); dotIdx >= 0 {
+		locale = locale[:dotIdx]
+	}
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+func (c *MessageCatalog) locale(locale string) *catalogLocale {
+	locale = normalizeLocale(locale)
+	cl, hasLocale := c.locales[locale]
+	if !hasLocale {
+		cl = &catalogLocale{messages: make(map[string]catalogMessage)}
+		c.locales[locale] = cl
+	}
+	return cl
+}
+
+func (c *MessageCatalog) AddMessage(locale, key, message string) {
+	c.locale(locale).messages[key] = catalogMessage{
+		categories: map[PluralCategory]string{PluralOther: message},
+	}
+}
+
+// AddPluralMessage adds a message that has a form for each of the CLDR
+// plural categories of the locale. The PluralOther form is used for the
+// categories that were not given.
+func (c *MessageCatalog) AddPluralMessage(locale, key string, forms map[PluralCategory]string) {
+	categories := make(map[PluralCategory]string, len(forms))
+	for category, form := range forms {
+		categories[category] = form
+	}
+	c.locale(locale).messages[key] = catalogMessage{categories: categories}
+}
+
+// LoadJSON adds the messages of a JSON object. Nested objects make up the
+// keys of their messages using dots (e.g. `cart.items`) unless all of
+// their keys are plural categories, in which case they are a plural
+// message.
+//
+//	{"welcome": {"title": "Welcome, {name}!"},
+//	 "cart": {"items": {"one": "{count} item", "other": "{count} items"}}}
+func (c *MessageCatalog) LoadJSON(locale string, r io.Reader) error {
+	var messages map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&messages); err != nil {
+		return fmt.Errorf("catalog: %v", err)
+	}
+	if err := c.addJSONMessages(locale, "", messages); err != nil {
+		return fmt.Errorf("catalog: %v", err)
+	}
+	return nil
+}
+
+func (c *MessageCatalog) addJSONMessages(locale, prefix string, messages map[string]interface{}) error {
+	for name, value := range messages {
+		key := prefix + name
+		switch value := value.(type) {
+		case string:
+			c.AddMessage(locale, key, value)
+		case map[string]interface{}:
+			forms, isPlural := jsonPluralForms(value)
+			if isPlural {
+				c.AddPluralMessage(locale, key, forms)
+			} else if err := c.addJSONMessages(locale, key+".", value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("`%v`: expecting a string or an object, got %T", key, value)
+		}
+	}
+	return nil
+}
+
+func jsonPluralForms(object map[string]interface{}) (map[PluralCategory]string, bool) {
+	if _, hasOther := object[string(PluralOther)]; !hasOther {
+		return nil, false
+	}
+	forms := make(map[PluralCategory]string, len(object))
+	for name, value := range object {
+		form, isString := value.(string)
+		if !isString || !isPluralCategory(name) {
+			return nil, false
+		}
+		forms[PluralCategory(name)] = form
+	}
+	return forms, true
+}
+
+type poEntry struct {
+	msgctxt     string
+	msgid       string
+	msgidPlural string
+	msgstrs     map[int]*string
+	fuzzy       bool
+}
+
+// LoadPO adds the messages of a gettext .po file. The msgid of an entry
+// is its key, which is prefixed with its msgctxt and a dot if it has one
+// (e.g. `menu.Open`) like the nested objects of LoadJSON. Entries that
+// are fuzzy or not translated are skipped. The plural forms are selected
+// using the Plural-Forms header of the file, or `n != 1` if it does not
+// have one.
+func (c *MessageCatalog) LoadPO(locale string, r io.Reader) error {
+	cl := c.locale(locale)
+
+	var entry *poEntry
+	var field *string
+	flush := func() error {
+		defer func() { entry, field = nil, nil }()
+		if entry == nil || entry.msgstrs == nil {
+			return nil
+		}
+		if entry.msgid == "" && entry.msgctxt == "" {
+			return cl.readPOHeader(*entry.msgstrs[0])
+		}
+		if entry.fuzzy {
+			return nil
+		}
+		forms := make([]string, len(entry.msgstrs))
+		for formIdx := range forms {
+			form, hasForm := entry.msgstrs[formIdx]
+			if !hasForm || *form == "" {
+				return nil
+			}
+			forms[formIdx] = *form
+		}
+		if entry.msgidPlural == "" {
+			forms = forms[:1]
+		}
+		key := entry.msgid
+		if entry.msgctxt != "" {
+			key = entry.msgctxt + "." + key
+		}
+		cl.messages[key] = catalogMessage{forms: forms}
+		return nil
+	}
+	// an entry ends on a blank line or where the next one starts
+	startEntry := func() error {
+		if entry != nil && entry.msgstrs != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if entry == nil {
+			entry = &poEntry{}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if err := func() error {
+			line := strings.TrimSpace(scanner.Text())
+			switch {
+			case line == "":
+				return flush()
+			case strings.HasPrefix(line, "#,"):
+				if err := startEntry(); err != nil {
+					return err
+				}
+				entry.fuzzy = entry.fuzzy || strings.Contains(line, "fuzzy")
+				return nil
+			case strings.HasPrefix(line, "#"):
+				return nil
+			case strings.HasPrefix(line, "\""):
+				str, err := strconv.Unquote(line)
+				if err != nil {
+					return fmt.Errorf("`%v` is not a valid string", line)
+				} else if field == nil {
+					return fmt.Errorf("unexpected string `%v`", line)
+				}
+				*field += str
+				return nil
+			}
+
+			keyword, quoted := line, ""
+			if spaceIdx := strings.IndexByte(line, ' '); spaceIdx >= 0 {
+				keyword, quoted = line[:spaceIdx], strings.TrimSpace(line[spaceIdx+1:])
+			}
+			str, err := strconv.Unquote(quoted)
+			if err != nil {
+				return fmt.Errorf("`%v` is not a valid string", quoted)
+			}
+
+			switch {
+			case keyword == "msgctxt", keyword == "msgid":
+				if err := startEntry(); err != nil {
+					return err
+				}
+				field = &entry.msgctxt
+				if keyword == "msgid" {
+					field = &entry.msgid
+				}
+			case keyword == "msgid_plural" && entry != nil:
+				field = &entry.msgidPlural
+			case (keyword == "msgstr" || strings.HasPrefix(keyword, "msgstr[")) && entry != nil:
+				formIdx := 0
+				if keyword != "msgstr" {
+					indexStr := strings.TrimSuffix(strings.TrimPrefix(keyword, "msgstr["), "]")
+					if formIdx, err = strconv.Atoi(indexStr); err != nil || formIdx < 0 {
+						return fmt.Errorf("`%v` is not a valid keyword", keyword)
+					}
+				}
+				if entry.msgstrs == nil {
+					entry.msgstrs = make(map[int]*string)
+				}
+				field = new(string)
+				entry.msgstrs[formIdx] = field
+			default:
+				return fmt.Errorf("unexpected `%v`", keyword)
+			}
+			*field = str
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("catalog: line %d: %v", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("catalog: %v", err)
+	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("catalog: %v", err)
+	}
+	return nil
+}
+
+func (cl *catalogLocale) readPOHeader(header string) error {
+	for _, line := range strings.Split(header, "\n") {
+		name, value := line, ""
+		if colonIdx := strings.IndexByte(line, ':'); colonIdx >= 0 {
+			name, value = line[:colonIdx], line[colonIdx+1:]
+		}
+		if strings.TrimSpace(name) != "Plural-Forms" {
+			continue
+		}
+		for _, part := range strings.Split(value, ";") {
+			part = strings.TrimSpace(part)
+			if strings.HasPrefix(part, "plural=") {
+				formula := strings.TrimSpace(strings.TrimPrefix(part, "plural="))
+				if _, err := parseExpression(formula); err != nil {
+					return fmt.Errorf("invalid Plural-Forms: %v", err)
+				}
+				cl.pluralFormula = formula
+			}
+		}
+	}
+	return nil
+}
+
+// pluralFormEvaluator evaluates the Plural-Forms of the .po files. Their
+// formulas are C expressions which are also valid native expressions.
+var pluralFormEvaluator = NewNativeEvaluator()
+
+func (cl *catalogLocale) pluralFormIndex(count interface{}) (int, error) {
+	operands, isNumber := newPluralOperands(count)
+	if !isNumber {
+		return 0, fmt.Errorf("expecting a number as the count, got %T", count)
+	}
+	formula := cl.pluralFormula
+	if formula == "" {
+		formula = "n != 1"
+	}
+	result, err := pluralFormEvaluator.Evaluate(formula, EvaluatorParams{"n": operands.i})
+	if err != nil {
+		return 0, err
+	}
+	switch result := result.(type) {
+	case bool:
+		if result {
+			return 1, nil
+		}
+		return 0, nil
+	case int:
+		return result, nil
+	default:
+		return 0, fmt.Errorf("plural form `%v` evaluated to %T", formula, result)
+	}
+}
+
+func (c *MessageCatalog) Message(locale, key string, count interface{}) (string, bool) {
+	for _, candidate := range c.localeCandidates(locale) {
+		cl, hasLocale := c.locales[candidate]
+		if !hasLocale {
+			continue
+		}
+		message, hasMessage := cl.messages[key]
+		if !hasMessage {
+			continue
+		}
+
+		if message.forms != nil {
+			if count == nil {
+				return message.forms[0], true
+			}
+			formIdx, err := cl.pluralFormIndex(count)
+			if err != nil || formIdx >= len(message.forms) {
+				formIdx = len(message.forms) - 1
+			}
+			return message.forms[formIdx], true
+		}
+		if count != nil {
+			if form, hasForm := message.categories[pluralCategoryOf(candidate, count)]; hasForm {
+				return form, true
+			}
+		}
+		form, hasForm := message.categories[PluralOther]
+		return form, hasForm
+	}
+	return "", false
+}
+
+// localeCandidates returns the locales whose messages are looked up for
+// the locale, from the most specific to the default locale.
+func (c *MessageCatalog) localeCandidates(locale string) []string {
+	var candidates []string
+	for _, l := range []string{locale, c.defaultLocale} {
+		if l == "" {
+			continue
+		}
+		l = normalizeLocale(l)
+		candidates = append(candidates, l)
+		if language := localeLanguage(l); language != l {
+			candidates = append(candidates, language)
+		}
+	}
+	return candidates
+}
+
+// renderLocale returns the locale of the node's scope, or the locale that
+// was given as a dependency using LocaleExtDepKey.
+func renderLocale(dependencies ExtensionDependencies, scope scopeChain) string {
+	if locale, hasLocale := scopeLocale(scope); hasLocale {
+		return locale
+	}
+	locale, _ := dependencies.Get(LocaleExtDepKey).(string)
+	return locale
+}
+
+func scopeLocale(scope scopeChain) (string, bool) {
+	locale, _ := scope.lookup(LocaleParam)
+	switch locale := locale.(type) {
+	case string:
+		return locale, true
+	case fmt.Stringer:
+		return locale.String(), true
+	default:
+		return "", false
+	}
+}
+
+// translate looks up the message of the key and replaces its
+// placeholders. The args can be a number, which is the count, and a map
+// or a struct, which has the values of the named placeholders. The count
+// is also the `{count}` placeholder and it can be given as a placeholder
+// value instead.
+func translate(
+	node *Node, dependencies ExtensionDependencies,
+	scope scopeChain, key string, args []interface{},
+) (string, error) {
+	catalog, hasCatalog := dependencies.Get(CatalogExtDepKey).(Catalog)
+	if !hasCatalog {
+		return "", fmt.Errorf("i18n: there is no catalog to look up `%v` from", key)
+	}
+
+	var count interface{}
+	placeholders := make(EvaluatorParams)
+	for argIdx, arg := range args {
+		if _, isNumber := toNumber(arg); isNumber {
+			count = arg
+			continue
+		}
+		argParams, err := ValueParams(arg)
+		if err != nil {
+			return "", fmt.Errorf("i18n: `%v`: argument %d: %v", key, argIdx+2, err)
+		}
+		for name, value := range argParams {
+			placeholders[name] = value
+		}
+	}
+	if count == nil {
+		count = placeholders["count"]
+	} else if _, hasCount := placeholders["count"]; !hasCount {
+		placeholders["count"] = count
+	}
+
+	locale := renderLocale(dependencies, scope)
+	message, hasMessage := catalog.Message(locale, key, count)
+	if !hasMessage {
+		err := &MissingMessageError{Locale: locale, Key: key, Position: node.Position()}
+		report, hasDiagnostics := dependencies.Get(DiagnosticsExtDepKey).(DiagnosticFunc)
+		if !hasDiagnostics {
+			return "", err
+		}
+		report(err)
+		return key, nil
+	}
+	return replacePlaceholders(dependencies, message, placeholders)
+}
+
+// replacePlaceholders replaces the `{name}` placeholders of the message.
+// The placeholders without a value are kept as they are.
+func replacePlaceholders(
+	dependencies ExtensionDependencies, message string, placeholders EvaluatorParams,
+) (string, error) {
+	var sb strings.Builder
+	for {
+		openIdx := strings.IndexByte(message, '{')
+		if openIdx < 0 {
+			break
+		}
+		closeIdx := strings.IndexByte(message[openIdx:], '}')
+		if closeIdx < 0 {
+			break
+		}
+		closeIdx += openIdx
+
+		name := strings.TrimSpace(message[openIdx+1 : closeIdx])
+		value, hasValue := placeholders[name]
+		if !hasValue {
+			sb.WriteString(message[:closeIdx+1])
+			message = message[closeIdx+1:]
+			continue
+		}
+		formatted, err := formatValue(dependencies, value)
+		if err != nil {
+			return "", fmt.Errorf("i18n: placeholder `%v`: %v", name, err)
+		}
+		sb.WriteString(message[:openIdx])
+		sb.WriteString(formatted)
+		message = message[closeIdx+1:]
+	}
+	sb.WriteString(message)
+	return sb.String(), nil
+}
+
+// translateFunc returns the `t` function of the expressions that are
+// evaluated on the node. The error of the last call is kept in lastErr
+// so that it can be returned as it is instead of the evaluator's error.
+func translateFunc(
+	node *Node, dependencies ExtensionDependencies,
+	scope scopeChain, lastErr *error,
+) func(string, ...interface{}) (string, error) {
+	return func(key string, args ...interface{}) (string, error) {
+		message, err := translate(node, dependencies, scope, key, args)
+		*lastErr = err
+		return message, err
+	}
+}
+
+type TranslateExtension struct {
+	key              string
+	paramsExpression string
+}
+
+func (te *TranslateExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
+	var args []interface{}
+	if te.paramsExpression != "" {
+		result, err := evaluatePipeline(node, dependencies, te.paramsExpression, params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("translate ext: %w", err)
+		}
+		if _, isMissing := result.(missingValue); !isMissing && result != nil {
+			args = append(args, result)
+		}
+	}
+
+	message, err := translate(node, dependencies, newScopeChain(node, params), te.key, args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("translate ext: %w", err)
+	}
+
+	copyNode := CopyNode(node)
+	for child := copyNode.FirstChild(); child != nil; child = copyNode.FirstChild() {
+		copyNode.RemoveChild(child)
+	}
+	copyNode.AppendChild(&Node{
+		Type:     html.TextNode,
+		Data:     html.EscapeString(message),
+		position: node.position,
+	})
+	return copyNode, nil, nil
+}
+
+func (te *TranslateExtension) Expressions() []string {
+	if te.paramsExpression == "" {
+		return nil
+	}
+	return []string{te.paramsExpression}
+}
+
+// TranslateExtensionNodeProcessor replaces the children of the elements
+// that have a `go-t` attribute with their translated message. The values
+// of its placeholders can be given using `go-t-params`.
+func TranslateExtensionNodeProcessor(node *Node) {
+	if hasKey, _, key := node.HasAttribute("go-t"); hasKey {
+		translateExtension := &TranslateExtension{key: strings.TrimSpace(key)}
+		node.RemoveAttribute("go-t")
+
+		if hasParams, _, paramsExpression := node.HasAttribute("go-t-params"); hasParams {
+			translateExtension.paramsExpression = paramsExpression
+			node.RemoveAttribute("go-t-params")
+		}
+
+		node.AddExtension(translateExtension)
+	}
+}
@@ -0,0 +1,310 @@
+package tplinator_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+type i18nExtDep struct {
+	catalog     tplinator.Catalog
+	diagnostics tplinator.DiagnosticFunc
+}
+
+func (ed i18nExtDep) Get(dependencyKey tplinator.DependencyKey) interface{} {
+	switch dependencyKey {
+	case tplinator.CatalogExtDepKey:
+		return ed.catalog
+	case tplinator.DiagnosticsExtDepKey:
+		if ed.diagnostics != nil {
+			return ed.diagnostics
+		}
+	}
+	return nil
+}
+
+const enMessages = `{
+	"welcome": {"title": "Welcome, {name}!"},
+	"cart": {
+		"items": {"one": "{count} item", "other": "{count} items"},
+		"empty": "Your cart is empty"
+	}
+}`
+
+const ruMessages = `{
+	"cart": {
+		"items": {
+			"one": "{count} товар",
+			"few": "{count} товара",
+			"many": "{count} товаров",
+			"other": "{count} товара"
+		}
+	}
+}`
+
+const plMessages = `# Polish messages
+msgid ""
+msgstr ""
+"Language: pl\n"
+"Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && "
+"(n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+msgid "welcome.title"
+msgstr "Witaj, "
+"{name}!"
+
+#, fuzzy
+msgid "cart.empty"
+msgstr "Koszyk jest pusty"
+
+msgid "cart.items"
+msgid_plural "cart.items"
+msgstr[0] "{count} produkt"
+msgstr[1] "{count} produkty"
+msgstr[2] "{count} produktów"
+msgid "cart.total"
+msgstr ""
+
+msgctxt "menu"
+msgid "open"
+msgstr "Otwórz"
+`
+
+func newTestCatalog(t *testing.T) *tplinator.MessageCatalog {
+	catalog := tplinator.NewMessageCatalog("en")
+	if err := catalog.LoadJSON("en", strings.NewReader(enMessages)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := catalog.LoadJSON("ru", strings.NewReader(ruMessages)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := catalog.LoadPO("pl_PL", strings.NewReader(plMessages)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return catalog
+}
+
+func TestMessageCatalog(t *testing.T) {
+	catalog := newTestCatalog(t)
+
+	testCases := []struct {
+		name   string
+		locale string
+		key    string
+		count  interface{}
+
+		expected      string
+		expectedFound bool
+	}{
+		{name: "json", locale: "en", key: "welcome.title", expected: "Welcome, {name}!", expectedFound: true},
+		{name: "json one", locale: "en", key: "cart.items", count: 1, expected: "{count} item", expectedFound: true},
+		{name: "json other", locale: "en", key: "cart.items", count: 1.5, expected: "{count} items", expectedFound: true},
+		{name: "json few", locale: "ru", key: "cart.items", count: 22, expected: "{count} товара", expectedFound: true},
+		{name: "json many", locale: "ru", key: "cart.items", count: 11, expected: "{count} товаров", expectedFound: true},
+		{name: "region fallback", locale: "ru-RU", key: "cart.items", count: 21, expected: "{count} товар", expectedFound: true},
+		{name: "default locale fallback", locale: "ru", key: "cart.empty", expected: "Your cart is empty", expectedFound: true},
+		{name: "po", locale: "pl-PL", key: "welcome.title", expected: "Witaj, {name}!", expectedFound: true},
+		{name: "po singular", locale: "pl-PL", key: "cart.items", count: 1, expected: "{count} produkt", expectedFound: true},
+		{name: "po few", locale: "pl-PL", key: "cart.items", count: 24, expected: "{count} produkty", expectedFound: true},
+		{name: "po many", locale: "pl-PL", key: "cart.items", count: 12, expected: "{count} produktów", expectedFound: true},
+		{name: "po fuzzy", locale: "pl-PL", key: "cart.empty", expected: "Your cart is empty", expectedFound: true},
+		{name: "po context", locale: "pl-PL", key: "menu.open", expected: "Otwórz", expectedFound: true},
+		{name: "po untranslated", locale: "pl-PL", key: "cart.total", expectedFound: false},
+		{name: "missing", locale: "en", key: "cart.checkout", expectedFound: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, found := catalog.Message(tc.locale, tc.key, tc.count)
+			if found != tc.expectedFound {
+				t.Errorf("wanted found to be %v, got %v", tc.expectedFound, found)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMessageCatalog_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		load func(*tplinator.MessageCatalog) error
+
+		expectedError string
+	}{
+		{
+			name: "json value",
+			load: func(c *tplinator.MessageCatalog) error {
+				return c.LoadJSON("en", strings.NewReader(`{"cart": {"size": 3}}`))
+			},
+			expectedError: "`cart.size`: expecting a string or an object",
+		},
+		{
+			name: "po keyword",
+			load: func(c *tplinator.MessageCatalog) error {
+				return c.LoadPO("en", strings.NewReader("msgid \"a\"\nmsgtxt \"b\"\n"))
+			},
+			expectedError: "line 2: unexpected `msgtxt`",
+		},
+		{
+			name: "po plural forms",
+			load: func(c *tplinator.MessageCatalog) error {
+				return c.LoadPO("en", strings.NewReader(
+					"msgid \"\"\nmsgstr \"Plural-Forms: nplurals=2; plural=(n > );\\n\"\n",
+				))
+			},
+			expectedError: "invalid Plural-Forms",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.load(tplinator.NewMessageCatalog("en"))
+			if err == nil {
+				t.Errorf("expecting an error")
+			} else if !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("wanted an error containing `%v`, got `%v`", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestPluralRules(t *testing.T) {
+	forms := map[tplinator.PluralCategory]string{
+		tplinator.PluralZero:  "zero",
+		tplinator.PluralOne:   "one",
+		tplinator.PluralTwo:   "two",
+		tplinator.PluralFew:   "few",
+		tplinator.PluralMany:  "many",
+		tplinator.PluralOther: "other",
+	}
+	locales := []string{"en", "fr", "ru", "pl", "cs", "ar", "ja"}
+	catalog := tplinator.NewMessageCatalog("en")
+	for _, locale := range locales {
+		catalog.AddPluralMessage(locale, "n", forms)
+	}
+
+	testCases := []struct {
+		locale   string
+		expected map[interface{}]string
+	}{
+		{locale: "en", expected: map[interface{}]string{0: "other", 1: "one", 2: "other", 1.5: "other", -1: "one"}},
+		{locale: "fr", expected: map[interface{}]string{0: "one", 1: "one", 1.5: "one", 2: "other"}},
+		{locale: "ru", expected: map[interface{}]string{1: "one", 21: "one", 11: "many", 3: "few", 13: "many", 25: "many", 1.5: "other"}},
+		{locale: "pl", expected: map[interface{}]string{1: "one", 21: "many", 22: "few", 12: "many", 0: "many", 2.5: "other"}},
+		{locale: "cs", expected: map[interface{}]string{1: "one", 3: "few", 5: "other", 0.5: "many"}},
+		{locale: "ar", expected: map[interface{}]string{0: "zero", 1: "one", 2: "two", 103: "few", 111: "many", 100: "other", 0.5: "other"}},
+		{locale: "ja", expected: map[interface{}]string{1: "other", 2: "other"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.locale, func(t *testing.T) {
+			for count, expected := range tc.expected {
+				if actual, _ := catalog.Message(tc.locale, "n", count); actual != expected {
+					t.Errorf("%v: wanted `%v`, got `%v`", count, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslate_Template(t *testing.T) {
+	template := "<div>\n" +
+		`  <h1 go-t="welcome.title" go-t-params="{name: user.name}">Welcome!</h1>` + "\n" +
+		`  <p title="{{go:t('cart.items', count)}}">{{go:t("cart.items", {count: count})}}</p>` + "\n" +
+		`  <i go-range="carts">{{go:t('cart.items', size)}}</i>` + "\n" +
+		`  <b go-text="t('welcome.title', user) | truncate(20)" :title="t('welcome.title', user)">x</b>` + "\n" +
+		"</div>"
+	params := tplinator.EvaluatorParams{
+		"user":  map[string]interface{}{"name": "<Larry>"},
+		"count": 3,
+		"carts": tplinator.RangeParams(
+			tplinator.EvaluatorParams{"size": 1},
+			tplinator.EvaluatorParams{"size": 5, "locale": "ru"},
+		),
+	}
+
+	tpl, err := tplinator.Tplinate(strings.NewReader(template), tplinator.EvaluatorParserOption(tplinator.NewNativeEvaluator()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tpl.AddExtensionDependencies(i18nExtDep{catalog: newTestCatalog(t)})
+	if err := tplinator.Check(tpl, reflect.TypeOf(params)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	actual, err := tpl.RenderString(params)
+	expected := `<div><h1>Welcome, &lt;Larry&gt;!</h1><p title="3 items">3 items</p>` +
+		`<i>1 item</i><i>5 товаров</i><b title="Welcome, &lt;Larry&gt;!">Welcome, &lt;Larry&gt;!</b></div>`
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+
+	params["locale"] = "pl_PL"
+	actual, err = tpl.RenderString(params)
+	expected = `<div><h1>Witaj, &lt;Larry&gt;!</h1><p title="3 produkty">3 produkty</p>` +
+		`<i>1 produkt</i><i>5 товаров</i><b title="Witaj, &lt;Larry&gt;!">Witaj, &lt;Larry&gt;!</b></div>`
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+}
+
+func TestTranslate_MissingMessage(t *testing.T) {
+	template := "<div>\n" +
+		`  <p>{{go:t('cart.checkout')}}</p><h1 go-t="welcome.subtitle"></h1>` + "\n" +
+		"</div>"
+
+	tpl, err := tplinator.Tplinate(strings.NewReader(template))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tpl.AddExtensionDependencies(i18nExtDep{catalog: newTestCatalog(t)})
+
+	_, err = tpl.RenderString(tplinator.EvaluatorParams{"locale": "ru"})
+	var missingMsgErr *tplinator.MissingMessageError
+	if !errors.As(err, &missingMsgErr) {
+		t.Fatalf("expecting a *MissingMessageError, got `%v`", err)
+	}
+	expected := tplinator.MissingMessageError{
+		Locale:   "ru",
+		Key:      "welcome.subtitle",
+		Position: tplinator.Position{Line: 2, Column: 35},
+	}
+	if *missingMsgErr != expected {
+		t.Errorf("wanted `%v`, got `%v`", &expected, missingMsgErr)
+	}
+
+	var diagnostics []string
+	tpl, err = tplinator.Tplinate(strings.NewReader(template))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tpl.AddExtensionDependencies(i18nExtDep{
+		catalog: newTestCatalog(t),
+		diagnostics: func(err error) {
+			diagnostics = append(diagnostics, err.Error())
+		},
+	})
+
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := `<div><p>cart.checkout</p><h1>welcome.subtitle</h1></div>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+	expectedDiagnostics := []string{
+		"line 2, column 35: missing message `welcome.subtitle`",
+		"line 2, column 6: missing message `cart.checkout`",
+	}
+	if strings.Join(diagnostics, "\n") != strings.Join(expectedDiagnostics, "\n") {
+		t.Errorf("wanted `%v`, got `%v`", expectedDiagnostics, diagnostics)
+	}
+}
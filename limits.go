@@ -0,0 +1,145 @@
+package tplinator
+
+import (
+	"fmt"
+)
+
+// Limits are the budgets of a single rendering of a template. They are
+// meant for templates that are not fully trusted, e.g. templates that can
+// be edited by customers. A zero value means that there's no limit.
+type Limits struct {
+	// MaxExpressionLength is the maximum number of bytes of an
+	// expression, including its filters.
+	MaxExpressionLength int
+	// MaxOperations is the maximum number of operations (e.g. literals,
+	// variables, operators, calls) of a single evaluation of an
+	// expression. It needs an evaluator that counts them, like the native
+	// one; rendering fails if the evaluator is the govaluate one or is not
+	// a ConfigurableEvaluator.
+	MaxOperations int
+	// MaxRangeIterations is the maximum number of items of all the ranges
+	// of the template combined.
+	MaxRangeIterations int
+	// MaxDepth is the maximum nesting depth of the rendered nodes.
+	MaxDepth int
+	// MaxOutputBytes is the maximum size of the rendered template.
+	MaxOutputBytes int
+}
+
+func LimitsParserOption(limits Limits) ParserOptionFunc {
+	return func(p *Parser) {
+		p.limits = &limits
+	}
+}
+
+type Limit int
+
+const (
+	ExpressionLengthLimit Limit = iota + 1
+	OperationsLimit
+	RangeIterationsLimit
+	DepthLimit
+	OutputBytesLimit
+)
+
+func (l Limit) String() string {
+	switch l {
+	case ExpressionLengthLimit:
+		return "expression length"
+	case OperationsLimit:
+		return "number of operations per evaluation"
+	case RangeIterationsLimit:
+		return "number of range iterations"
+	case DepthLimit:
+		return "nesting depth"
+	case OutputBytesLimit:
+		return "output size in bytes"
+	default:
+		return fmt.Sprintf("Limit(%d)", int(l))
+	}
+}
+
+// LimitError is returned when rendering a template exceeded one of its
+// Limits. The rendering is aborted and its output is incomplete.
+type LimitError struct {
+	Limit    Limit
+	Max      int
+	Position Position
+}
+
+func (e *LimitError) Error() string {
+	msg := fmt.Sprintf("exceeded the maximum %v of %d", e.Limit, e.Max)
+	if e.Position.IsValid() {
+		msg = e.Position.String() + ": " + msg
+	}
+	return msg
+}
+
+// renderBudget keeps track of the usage of the Limits that span a whole
+// rendering of a template.
+type renderBudget struct {
+	limits Limits
+
+	rangeIterations int
+	outputBytes     int
+}
+
+func (rb *renderBudget) useRangeIterations(node *Node, iterations int) error {
+	rb.rangeIterations += iterations
+	if max := rb.limits.MaxRangeIterations; max > 0 && rb.rangeIterations > max {
+		return &LimitError{Limit: RangeIterationsLimit, Max: max, Position: node.Position()}
+	}
+	return nil
+}
+
+func (rb *renderBudget) useDepth(node *Node, depth int) error {
+	if max := rb.limits.MaxDepth; max > 0 && depth > max {
+		return &LimitError{Limit: DepthLimit, Max: max, Position: node.Position()}
+	}
+	return nil
+}
+
+func (rb *renderBudget) useOutputBytes(node *Node, bytes int) error {
+	rb.outputBytes += bytes
+	if max := rb.limits.MaxOutputBytes; max > 0 && rb.outputBytes > max {
+		return &LimitError{Limit: OutputBytesLimit, Max: max, Position: node.Position()}
+	}
+	return nil
+}
+
+// renderExtensionDependencies gives the extensions access to the budget
+// of the rendering that they are applied on.
+type renderExtensionDependencies struct {
+	ExtensionDependencies
+	budget *renderBudget
+}
+
+const renderBudgetExtDepKey DependencyKey = "tplinator.renderBudget"
+
+func (ed renderExtensionDependencies) Get(dependencyKey DependencyKey) interface{} {
+	if dependencyKey == renderBudgetExtDepKey {
+		return ed.budget
+	}
+	return ed.ExtensionDependencies.Get(dependencyKey)
+}
+
+// countsOperations reports whether the evaluator enforces
+// EvaluatorOptions#MaxOperations. The custom ConfigurableEvaluators are
+// trusted to do so.
+func countsOperations(evaluator interface{}) bool {
+	switch evaluator.(type) {
+	case *govaluator:
+		return false
+	case ConfigurableEvaluator:
+		return true
+	default:
+		return false
+	}
+}
+
+func renderLimits(dependencies ExtensionDependencies) Limits {
+	if limits, isLimits := dependencies.Get(LimitsExtDepKey).(Limits); isLimits {
+		return limits
+	}
+	return Limits{}
+}
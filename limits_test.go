@@ -0,0 +1,141 @@
+package tplinator_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+func TestLimits(t *testing.T) {
+	params := tplinator.EvaluatorParams{
+		"name":    "Larry",
+		"numbers": []int{1, 2, 3, 4, 5},
+		"naturals": func(yield func(int) bool) {
+			for n := 1; yield(n); n++ {
+			}
+		},
+		"items": tplinator.RangeParams(
+			tplinator.EvaluatorParams{"tags": tplinator.RangeParams(tplinator.EvaluatorParams{}, tplinator.EvaluatorParams{})},
+			tplinator.EvaluatorParams{"tags": tplinator.RangeParams(tplinator.EvaluatorParams{}, tplinator.EvaluatorParams{})},
+		),
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		limits   tplinator.Limits
+
+		expected      string
+		expectedLimit tplinator.Limit
+		expectedError string
+	}{
+		{
+			name:     "within the limits",
+			template: `<ul><li go-range="items"><b go-range="tags">{{go:name}}</b></li></ul>`,
+			limits: tplinator.Limits{
+				MaxExpressionLength: 5,
+				MaxOperations:       1,
+				MaxRangeIterations:  6,
+				MaxDepth:            4,
+				MaxOutputBytes:      92,
+			},
+			expected: `<ul><li><b>Larry</b><b>Larry</b></li><li><b>Larry</b><b>Larry</b></li></ul>`,
+		},
+		{
+			name:          "expression length",
+			template:      "<div>\n<p>{{go:name + name}}</p></div>",
+			limits:        tplinator.Limits{MaxExpressionLength: 10},
+			expectedLimit: tplinator.ExpressionLengthLimit,
+			expectedError: "line 2, column 4: exceeded the maximum expression length of 10",
+		},
+		{
+			name:          "operations",
+			template:      `<p go-if="name == 'Larry' && 5 in numbers">Hi</p>`,
+			limits:        tplinator.Limits{MaxOperations: 8},
+			expectedLimit: tplinator.OperationsLimit,
+			expectedError: "line 1, column 1: exceeded the maximum number of operations per evaluation of 8",
+		},
+		{
+			name:          "range iterations",
+			template:      "<ul>\n  <li go-range=\"items\"><b go-range=\"tags\"></b></li></ul>",
+			limits:        tplinator.Limits{MaxRangeIterations: 5},
+			expectedLimit: tplinator.RangeIterationsLimit,
+			expectedError: "line 2, column 24: exceeded the maximum number of range iterations of 5",
+		},
+		{
+			name:          "range iterations of an endless iterator",
+			template:      `<ul><li go-range="n in naturals">{{go:n}}</li></ul>`,
+			limits:        tplinator.Limits{MaxRangeIterations: 100},
+			expectedLimit: tplinator.RangeIterationsLimit,
+			expectedError: "line 1, column 5: exceeded the maximum number of range iterations of 100",
+		},
+		{
+			name:          "depth",
+			template:      `<div><ul><li><b>{{go:name}}</b></li></ul></div>`,
+			limits:        tplinator.Limits{MaxDepth: 4},
+			expectedLimit: tplinator.DepthLimit,
+			expectedError: "line 1, column 17: exceeded the maximum nesting depth of 4",
+		},
+		{
+			name:          "output bytes",
+			template:      `<ul><li go-range="items">{{go:name}}</li></ul>`,
+			limits:        tplinator.Limits{MaxOutputBytes: 30},
+			expectedLimit: tplinator.OutputBytesLimit,
+			expectedError: "line 1, column 5: exceeded the maximum output size in bytes of 30",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(
+				strings.NewReader(tc.template),
+				tplinator.LimitsParserOption(tc.limits),
+				tplinator.EvaluatorParserOption(tplinator.NewNativeEvaluator()),
+			)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			var output strings.Builder
+			err = tpl.Render(params, func(str string) {
+				output.WriteString(str)
+			})
+
+			if tc.expectedLimit == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				} else if output.String() != tc.expected {
+					t.Errorf("wanted `%v`, got `%v`", tc.expected, output.String())
+				}
+				return
+			}
+
+			var limitErr *tplinator.LimitError
+			if !errors.As(err, &limitErr) {
+				t.Errorf("expecting a limit error, got `%v`", err)
+			} else if limitErr.Limit != tc.expectedLimit || limitErr.Error() != tc.expectedError {
+				t.Errorf("wanted `%v`, got `%v`", tc.expectedError, limitErr)
+			}
+			if tc.limits.MaxOutputBytes > 0 && output.Len() > tc.limits.MaxOutputBytes {
+				t.Errorf("expecting the output to be at most %d bytes, got `%v`", tc.limits.MaxOutputBytes, output.String())
+			}
+		})
+	}
+}
+
+func TestLimits_OperationsWithoutCounting(t *testing.T) {
+	tpl, err := tplinator.Tplinate(
+		strings.NewReader(`<p>{{go:name}}</p>`),
+		tplinator.LimitsParserOption(tplinator.Limits{MaxOperations: 8}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = tpl.RenderString(tplinator.EvaluatorParams{"name": "Larry"})
+	expected := "template: MaxOperations needs an evaluator that counts the operations, e.g. NewNativeEvaluator()"
+	if err == nil || err.Error() != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, err)
+	}
+}
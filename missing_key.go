@@ -0,0 +1,94 @@
+package tplinator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MissingKeyPolicy controls what happens when an expression uses a
+// variable, or a key of a map, that does not exist.
+type MissingKeyPolicy int
+
+const (
+	// MissingKeyError fails the rendering with a *MissingVariableError.
+	// It is used when no policy was set.
+	MissingKeyError MissingKeyPolicy = iota + 1
+	// MissingKeyZero makes the expression evaluate to nil, which renders
+	// as an empty string, is false on conditions and ranges over nothing.
+	MissingKeyZero
+	// MissingKeyPlaceholder works like MissingKeyZero but renders a
+	// visible placeholder (e.g. `[missing: user.name]`) instead of an
+	// empty string. It is meant to be used during development.
+	MissingKeyPlaceholder
+)
+
+func MissingKeyParserOption(policy MissingKeyPolicy) ParserOptionFunc {
+	return func(p *Parser) {
+		p.missingKey = policy
+	}
+}
+
+type MissingVariableError struct {
+	Name       string
+	Expression string
+	Position   Position
+}
+
+func (e *MissingVariableError) Error() string {
+	msg := "missing variable `" + e.Name + "`"
+	if e.Expression != "" && e.Expression != e.Name {
+		msg += " in `" + e.Expression + "`"
+	}
+	if e.Position.IsValid() {
+		msg = e.Position.String() + ": " + msg
+	}
+	return msg
+}
+
+// missingValue is the result of an expression that used a missing
+// variable when the MissingKeyPolicy allows it.
+type missingValue struct {
+	name   string
+	policy MissingKeyPolicy
+}
+
+func (mv missingValue) String() string {
+	if mv.policy == MissingKeyPlaceholder {
+		return "[missing: " + mv.name + "]"
+	}
+	return ""
+}
+
+func missingKeyPolicy(dependencies ExtensionDependencies) MissingKeyPolicy {
+	if policy, isPolicy := dependencies.Get(MissingKeyExtDepKey).(MissingKeyPolicy); isPolicy && policy != 0 {
+		return policy
+	}
+	return MissingKeyError
+}
+
+// handleMissingVariable applies the MissingKeyPolicy if the error was
+// caused by a missing variable. It returns the error, with the
+// expression and the position of the node, if the policy does not allow
+// missing variables or if the error was caused by something else.
+func handleMissingVariable(
+	node *Node, dependencies ExtensionDependencies, input string, err error,
+) (*missingValue, error) {
+	var missingVarErr *MissingVariableError
+	if !errors.As(err, &missingVarErr) {
+		return nil, err
+	}
+
+	policy := missingKeyPolicy(dependencies)
+	switch policy {
+	case MissingKeyZero, MissingKeyPlaceholder:
+		return &missingValue{name: missingVarErr.Name, policy: policy}, nil
+	case MissingKeyError:
+		return nil, &MissingVariableError{
+			Name:       missingVarErr.Name,
+			Expression: input,
+			Position:   node.Position(),
+		}
+	default:
+		return nil, fmt.Errorf("unknown missing key policy %d", policy)
+	}
+}
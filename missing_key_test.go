@@ -0,0 +1,224 @@
+package tplinator_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+type missingKeyExtDep struct {
+	policy tplinator.MissingKeyPolicy
+}
+
+func (ed missingKeyExtDep) Get(dependencyKey tplinator.DependencyKey) interface{} {
+	if dependencyKey == tplinator.MissingKeyExtDepKey {
+		return ed.policy
+	}
+	return nil
+}
+
+func TestMissingKeyPolicy(t *testing.T) {
+	template := "<div>\n" +
+		`  <h1 go-if="isAdmin">Admin</h1>` + "\n" +
+		`  <p go-if-class-new="user.isNew" title="{{go:user.title}}">{{go:user.name}}</p>` + "\n" +
+		`  <i go-range="items">{{go:name}}</i>` + "\n" +
+		`  <b>{{go:nickname | default('Guest')}}</b>` + "\n" +
+		"</div>"
+	params := tplinator.EvaluatorParams{
+		"user": map[string]interface{}{"name": "Larry"},
+	}
+
+	testCases := []struct {
+		name   string
+		policy tplinator.MissingKeyPolicy
+
+		expected string
+	}{
+		{
+			name:     "zero",
+			policy:   tplinator.MissingKeyZero,
+			expected: `<div><p title="">Larry</p><b>Guest</b></div>`,
+		},
+		{
+			name:   "placeholder",
+			policy: tplinator.MissingKeyPlaceholder,
+			expected: `<div><p title="[missing: user.title]">Larry</p>` +
+				`<b>[missing: nickname]</b></div>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(
+				strings.NewReader(template),
+				tplinator.MissingKeyParserOption(tc.policy),
+			)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			actual, err := tpl.RenderString(params)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMissingKeyPolicy_Operands(t *testing.T) {
+	template := `<div><p go-if="!hidden">shown</p>` +
+		`<b>{{go:'Hello ' + name}}</b><i>{{go:count + 1}}</i>` +
+		`<s go-if="count > 0 || isAdmin">admin</s><u go-if="(m) > 3 || !(hidden)">{{go:((n)) * (2)}}</u></div>`
+
+	testCases := []struct {
+		name      string
+		policy    tplinator.MissingKeyPolicy
+		evaluator tplinator.Evaluator
+
+		expected string
+	}{
+		{
+			name:      "zero/govaluate",
+			policy:    tplinator.MissingKeyZero,
+			evaluator: tplinator.NewGovaluateEvaluator(),
+			expected:  `<div><p>shown</p><b>Hello </b><i>1</i><u>0</u></div>`,
+		},
+		{
+			name:      "zero/native",
+			policy:    tplinator.MissingKeyZero,
+			evaluator: tplinator.NewNativeEvaluator(),
+			expected:  `<div><p>shown</p><b>Hello </b><i>1</i><u>0</u></div>`,
+		},
+		{
+			name:      "placeholder/govaluate",
+			policy:    tplinator.MissingKeyPlaceholder,
+			evaluator: tplinator.NewGovaluateEvaluator(),
+			expected:  `<div><p>shown</p><b>Hello [missing: name]</b><i>1</i><u>0</u></div>`,
+		},
+		{
+			name:      "placeholder/native",
+			policy:    tplinator.MissingKeyPlaceholder,
+			evaluator: tplinator.NewNativeEvaluator(),
+			expected:  `<div><p>shown</p><b>Hello [missing: name]</b><i>1</i><u>0</u></div>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(
+				strings.NewReader(template),
+				tplinator.MissingKeyParserOption(tc.policy),
+			)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			tpl.AddExtensionDependencies(evaluatorExtDep{evaluator: tc.evaluator})
+			actual, err := tpl.RenderString(tplinator.EvaluatorParams{})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMissingKeyPolicy_Error(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+
+		expected tplinator.MissingVariableError
+	}{
+		{
+			name:     "conditional",
+			template: "<div>\n  <h1 go-if=\"isAdmin\">Admin</h1>\n</div>",
+			expected: tplinator.MissingVariableError{
+				Name: "isAdmin", Expression: "isAdmin",
+				Position: tplinator.Position{Line: 2, Column: 3},
+			},
+		},
+		{
+			name:     "conditional class",
+			template: `<p go-if-class-new="user.isNew"></p>`,
+			expected: tplinator.MissingVariableError{
+				Name: "user.isNew", Expression: "user.isNew",
+				Position: tplinator.Position{Line: 1, Column: 1},
+			},
+		},
+		{
+			name:     "range",
+			template: "<ul>\n<li go-range=\"items | limit(3)\"></li></ul>",
+			expected: tplinator.MissingVariableError{
+				Name: "items", Expression: "items | limit(3)",
+				Position: tplinator.Position{Line: 2, Column: 1},
+			},
+		},
+		{
+			name:     "attribute interpolation",
+			template: `<a href="/users/{{go:user.id}}"></a>`,
+			expected: tplinator.MissingVariableError{
+				Name: "user.id", Expression: "user.id",
+				Position: tplinator.Position{Line: 1, Column: 1},
+			},
+		},
+		{
+			name:     "text interpolation",
+			template: "<p>\n  Hi, {{go:user.name | upper}}</p>",
+			expected: tplinator.MissingVariableError{
+				Name: "user.name", Expression: "user.name | upper",
+				Position: tplinator.Position{Line: 2, Column: 3},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(strings.NewReader(tc.template))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			_, err = tpl.RenderString(tplinator.EvaluatorParams{
+				"user": map[string]interface{}{},
+			})
+
+			var missingVarErr *tplinator.MissingVariableError
+			if !errors.As(err, &missingVarErr) {
+				t.Errorf("expecting a missing variable error, got `%v`", err)
+			} else if *missingVarErr != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected.Error(), missingVarErr)
+			}
+		})
+	}
+}
+
+func TestMissingKeyPolicy_ExtensionDependency(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<p>{{go:name}}</p>`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	tpl.AddExtensionDependencies(missingKeyExtDep{policy: tplinator.MissingKeyPlaceholder})
+
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<p>[missing: name]</p>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+}
+
+func TestMissingVariableError(t *testing.T) {
+	err := &tplinator.MissingVariableError{
+		Name: "name", Expression: "name | upper", Position: tplinator.Position{Line: 3, Column: 9},
+	}
+	if expected := "line 3, column 9: missing variable `name` in `name | upper`"; err.Error() != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, err.Error())
+	}
+}
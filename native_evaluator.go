@@ -0,0 +1,734 @@
+package tplinator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type nativeEvaluator struct {
+	funcs         FuncMap
+	maxOperations int
+	missingKey    MissingKeyPolicy
+
+	// the parsed expressions are shared by the evaluators that were
+	// created using WithOptions
+	cache *expressionCache
+}
+
+// maxCachedExpressions is the number of parsed expressions that an
+// evaluator keeps, so that evaluating expressions that are built at
+// runtime does not grow the cache forever.
+const maxCachedExpressions = 1024
+
+// expressionCache is a bounded cache of parsed expressions. The oldest
+// expression is evicted first.
+type expressionCache struct {
+	mutex sync.Mutex
+	nodes map[string]exprNode
+	order []string
+}
+
+func (ec *expressionCache) load(input string) (exprNode, bool) {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	node, isCached := ec.nodes[input]
+	return node, isCached
+}
+
+func (ec *expressionCache) store(input string, node exprNode) {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	if _, isCached := ec.nodes[input]; isCached {
+		return
+	}
+	if len(ec.order) >= maxCachedExpressions {
+		delete(ec.nodes, ec.order[0])
+		ec.order = ec.order[1:]
+	}
+	ec.nodes[input] = node
+	ec.order = append(ec.order, input)
+}
+
+// NewNativeEvaluator creates the Evaluator of the native expression
+// language. See the README for its grammar. It can be used instead of the
+// default govaluate evaluator using EvaluatorParserOption.
+func NewNativeEvaluator() Evaluator {
+	return &nativeEvaluator{cache: &expressionCache{nodes: map[string]exprNode{}}}
+}
+
+// NewGovaluateEvaluator creates an Evaluator that uses govaluate, which is
+// the default one. The expressions that use the syntax that govaluate
+// does not have (e.g. `1..5` or `{width: size}`) are evaluated using the
+// native expression language.
+func NewGovaluateEvaluator() Evaluator {
+	return &govaluator{native: NewNativeEvaluator().(*nativeEvaluator)}
+}
+
+func (e *nativeEvaluator) WithOptions(options EvaluatorOptions) Evaluator {
+	return &nativeEvaluator{
+		funcs:         options.Funcs,
+		maxOperations: options.MaxOperations,
+		missingKey:    options.MissingKey,
+		cache:         e.cache,
+	}
+}
+
+func (e *nativeEvaluator) parse(input string) (exprNode, error) {
+	if node, isCached := e.cache.load(input); isCached {
+		return node, nil
+	}
+	node, err := parseExpression(input)
+	if err != nil {
+		return nil, fmt.Errorf("evaluator: %v", err)
+	}
+	e.cache.store(input, node)
+	return node, nil
+}
+
+func (e *nativeEvaluator) Evaluate(input string, params EvaluatorParams) (interface{}, error) {
+	return e.evaluateScope(input, scopeChain{params})
+}
+
+func (e *nativeEvaluator) evaluateScope(input string, scope scopeChain) (interface{}, error) {
+	node, err := e.parse(input)
+	if err != nil {
+		return nil, err
+	}
+	evaluation := &nativeEvaluation{
+		input:         input,
+		scope:         scope,
+		funcs:         e.funcs,
+		maxOperations: e.maxOperations,
+		missingKey:    e.missingKey,
+	}
+	return evaluation.eval(node)
+}
+
+func (e *nativeEvaluator) EvaluateBool(input string, params EvaluatorParams) (bool, error) {
+	result, err := e.Evaluate(input, params)
+	if err != nil {
+		return false, err
+	}
+	boolResult, isBoolean := result.(bool)
+	if !isBoolean {
+		return false, fmt.Errorf("evaluator: `%v` is not a conditional expression", input)
+	}
+	return boolResult, nil
+}
+
+func (e *nativeEvaluator) EvaluateString(input string, params EvaluatorParams) (string, error) {
+	result, err := e.Evaluate(input, params)
+	if err != nil {
+		return "", err
+	}
+	stringResult, isString := result.(string)
+	if !isString {
+		return "", fmt.Errorf("evaluator: `%v` is not an expression that returns a string", input)
+	}
+	return stringResult, nil
+}
+
+type nativeEvaluation struct {
+	input string
+	scope scopeChain
+	funcs FuncMap
+
+	operations    int
+	maxOperations int
+	missingKey    MissingKeyPolicy
+}
+
+// useOperations counts the operations of the evaluation. Going through
+// the items of a collection counts as an operation per item.
+func (ne *nativeEvaluation) useOperations(operations int) error {
+	ne.operations += operations
+	if ne.maxOperations > 0 && ne.operations > ne.maxOperations {
+		return &LimitError{Limit: OperationsLimit, Max: ne.maxOperations}
+	}
+	return nil
+}
+
+// missingVariable is the value of a variable that does not exist. It is a
+// missingValue, which the operators treat as the zero value of the type of
+// their other operand, if the MissingKeyPolicy allows missing variables.
+func (ne *nativeEvaluation) missingVariable(name string) (interface{}, error) {
+	switch ne.missingKey {
+	case MissingKeyZero, MissingKeyPlaceholder:
+		return missingValue{name: name, policy: ne.missingKey}, nil
+	default:
+		return nil, &MissingVariableError{Name: name}
+	}
+}
+
+// zeroIfMissing turns a missing variable into nil before it is passed to
+// a function or put in a list or an object.
+func zeroIfMissing(value interface{}) interface{} {
+	if _, isMissing := value.(missingValue); isMissing {
+		return nil
+	}
+	return value
+}
+
+// zeroLike is the zero value of the type of the other operand of a binary
+// operator, which a missing operand is replaced with. A missing string
+// that is concatenated is rendered as the placeholder of the policy.
+func zeroLike(missing missingValue, other interface{}, op string) interface{} {
+	if isNilValue(other) {
+		return nil
+	}
+	if _, isString := other.(string); isString && op == "+" {
+		return missing.String()
+	}
+	return reflect.Zero(reflect.TypeOf(other)).Interface()
+}
+
+func (ne *nativeEvaluation) text(node exprNode) string {
+	span := node.span()
+	return ne.input[span.start:span.end]
+}
+
+func (ne *nativeEvaluation) errorf(node exprNode, format string, args ...interface{}) error {
+	return fmt.Errorf("evaluator: `%v`: %v", ne.text(node), fmt.Sprintf(format, args...))
+}
+
+func (ne *nativeEvaluation) eval(node exprNode) (interface{}, error) {
+	if err := ne.useOperations(1); err != nil {
+		return nil, err
+	}
+
+	switch node := node.(type) {
+	case *exprLiteral:
+		return node.value, nil
+	case *exprIdent:
+		value, hasValue := ne.scope.lookup(node.name)
+		if !hasValue {
+			return ne.missingVariable(node.name)
+		}
+		return value, nil
+	case *exprMember:
+		target, err := ne.eval(node.target)
+		if err != nil {
+			return nil, err
+		}
+		if _, isMissing := target.(missingValue); isMissing {
+			return target, nil
+		}
+		if node.optional && isNilValue(target) {
+			return nil, nil
+		}
+		return ne.member(node, target, node.name)
+	case *exprIndex:
+		return ne.evalIndex(node)
+	case *exprCall:
+		return ne.evalCall(node)
+	case *exprUnary:
+		return ne.evalUnary(node)
+	case *exprBinary:
+		return ne.evalBinary(node)
+	case *exprTernary:
+		condition, err := ne.evalBool(node.condition)
+		if err != nil {
+			return nil, err
+		}
+		if condition {
+			return ne.eval(node.then)
+		}
+		return ne.eval(node.otherwise)
+	case *exprList:
+		items := make([]interface{}, len(node.items))
+		for itemIdx, item := range node.items {
+			value, err := ne.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			items[itemIdx] = zeroIfMissing(value)
+		}
+		return items, nil
+	case *exprObject:
+		object := make(map[string]interface{}, len(node.keys))
+		for keyIdx, key := range node.keys {
+			value, err := ne.eval(node.values[keyIdx])
+			if err != nil {
+				return nil, err
+			}
+			object[key] = zeroIfMissing(value)
+		}
+		return object, nil
+	default:
+		return nil, fmt.Errorf("evaluator: unknown expression node %T", node)
+	}
+}
+
+func (ne *nativeEvaluation) member(node exprNode, target interface{}, name string) (interface{}, error) {
+	value, err := resolveMember(target, name)
+	if err != nil {
+		if errors.As(err, &memberNotFoundError{}) {
+			return ne.missingVariable(ne.text(node))
+		}
+		return nil, ne.errorf(node, "%v", err)
+	}
+	return value, nil
+}
+
+func (ne *nativeEvaluation) evalBool(node exprNode) (bool, error) {
+	value, err := ne.eval(node)
+	if err != nil {
+		return false, err
+	}
+	if _, isMissing := value.(missingValue); isMissing {
+		return false, nil
+	}
+	boolValue, isBool := value.(bool)
+	if !isBool {
+		return false, ne.errorf(node, "expecting a boolean, got %T", value)
+	}
+	return boolValue, nil
+}
+
+func (ne *nativeEvaluation) evalIndex(node *exprIndex) (interface{}, error) {
+	target, err := ne.eval(node.target)
+	if err != nil {
+		return nil, err
+	}
+	if _, isMissing := target.(missingValue); isMissing {
+		return target, nil
+	}
+	if node.optional && isNilValue(target) {
+		return nil, nil
+	}
+	index, err := ne.eval(node.index)
+	if err != nil {
+		return nil, err
+	}
+	index = zeroIfMissing(index)
+
+	rv, isValid := indirectValue(reflect.ValueOf(target))
+	if !isValid {
+		return nil, ne.errorf(node, "cannot index a nil value")
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		idx, err := toInt(index)
+		if err != nil {
+			return nil, ne.errorf(node, "index %v", err)
+		}
+		if idx < 0 || idx >= rv.Len() {
+			return nil, ne.errorf(node, "index %d is out of range [0:%d]", idx, rv.Len())
+		}
+		if rv.Kind() == reflect.String {
+			return string(rv.String()[idx]), nil
+		}
+		return rv.Index(idx).Interface(), nil
+	case reflect.Map:
+		key, err := convertArg(index, rv.Type().Key())
+		if err != nil {
+			return nil, ne.errorf(node, "key: %v", err)
+		}
+		value := rv.MapIndex(key)
+		if !value.IsValid() {
+			return ne.missingVariable(ne.text(node))
+		}
+		return value.Interface(), nil
+	default:
+		name, isName := index.(string)
+		if !isName {
+			return nil, ne.errorf(node, "cannot index %T using %T", target, index)
+		}
+		return ne.member(node, target, name)
+	}
+}
+
+func (ne *nativeEvaluation) evalCall(node *exprCall) (interface{}, error) {
+	var fn reflect.Value
+	var receiver interface{}
+	var methodName string
+
+	switch callee := node.callee.(type) {
+	case *exprIdent:
+		if registeredFn, isRegistered := ne.funcs[callee.name]; isRegistered {
+			fn = reflect.ValueOf(registeredFn)
+		} else if value, hasValue := ne.scope.lookup(callee.name); hasValue {
+			fn = reflect.ValueOf(value)
+		} else if builtinFn, isBuiltin := ne.builtinFunc(callee.name); isBuiltin {
+			fn = reflect.ValueOf(builtinFn)
+		} else {
+			return nil, fmt.Errorf("evaluator: unknown function `%v` in `%v`", callee.name, ne.input)
+		}
+	case *exprMember:
+		target, err := ne.eval(callee.target)
+		if err != nil {
+			return nil, err
+		}
+		if _, isMissing := target.(missingValue); isMissing {
+			return target, nil
+		}
+		if callee.optional && isNilValue(target) {
+			return nil, nil
+		}
+		receiver, methodName = target, callee.name
+	default:
+		value, err := ne.eval(callee)
+		if err != nil {
+			return nil, err
+		}
+		fn = reflect.ValueOf(value)
+	}
+
+	args := make([]interface{}, len(node.args))
+	for argIdx, arg := range node.args {
+		value, err := ne.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[argIdx] = zeroIfMissing(value)
+	}
+
+	var result interface{}
+	var err error
+	if methodName != "" {
+		result, err = callMethod(receiver, methodName, args)
+	} else {
+		result, err = callFunc(ne.text(node.callee), fn, args)
+	}
+	if err != nil {
+		return nil, ne.errorf(node.callee, "%v", err)
+	}
+	return result, nil
+}
+
+// builtinFunc returns a built-in function. The formatting functions use
+// the locale of the params so that they are available even if the
+// evaluator is used outside of a template.
+func (ne *nativeEvaluation) builtinFunc(name string) (interface{}, bool) {
+	if fn, isBuiltin := builtinFuncs[name]; isBuiltin {
+		return fn, true
+	}
+	locale, _ := scopeLocale(ne.scope)
+	fn, isBuiltin := newLocaleFormatter(locale).funcs()[name]
+	return fn, isBuiltin
+}
+
+func (ne *nativeEvaluation) evalUnary(node *exprUnary) (interface{}, error) {
+	if node.op == "!" {
+		operand, err := ne.evalBool(node.operand)
+		if err != nil {
+			return nil, err
+		}
+		return !operand, nil
+	}
+
+	operand, err := ne.eval(node.operand)
+	if err != nil {
+		return nil, err
+	}
+	if _, isMissing := operand.(missingValue); isMissing {
+		operand = 0
+	}
+	number, isNumber := toNumber(operand)
+	if !isNumber {
+		return nil, ne.errorf(node, "expecting a number, got %T", operand)
+	}
+	if node.op == "+" {
+		return number.value(), nil
+	}
+	if number.isInt {
+		return -number.intValue, nil
+	}
+	return -number.floatValue, nil
+}
+
+func (ne *nativeEvaluation) evalBinary(node *exprBinary) (interface{}, error) {
+	switch node.op {
+	case "&&", "||":
+		left, err := ne.evalBool(node.left)
+		if err != nil {
+			return nil, err
+		}
+		if left == (node.op == "||") {
+			return left, nil
+		}
+		return ne.evalBool(node.right)
+	case "??":
+		left, err := ne.eval(node.left)
+		var missingVarErr *MissingVariableError
+		if err != nil && !errors.As(err, &missingVarErr) {
+			return nil, err
+		}
+		if _, isMissing := left.(missingValue); err == nil && !isMissing && !isNilValue(left) {
+			return left, nil
+		}
+		return ne.eval(node.right)
+	}
+
+	left, err := ne.eval(node.left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := ne.eval(node.right)
+	if err != nil {
+		return nil, err
+	}
+
+	leftMissing, isLeftMissing := left.(missingValue)
+	rightMissing, isRightMissing := right.(missingValue)
+	switch {
+	case isLeftMissing && isRightMissing:
+		// both operands are the zero value of the same unknown type
+		switch node.op {
+		case "==", "<=", ">=":
+			return true, nil
+		case "!=", "<", ">", "in":
+			return false, nil
+		default:
+			return leftMissing, nil
+		}
+	case isLeftMissing:
+		if node.op == "in" {
+			left = nil
+		} else {
+			left = zeroLike(leftMissing, right, node.op)
+		}
+	case isRightMissing:
+		if node.op == "in" {
+			return false, nil
+		}
+		right = zeroLike(rightMissing, left, node.op)
+	}
+
+	switch node.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "<", "<=", ">", ">=":
+		comparison, err := compareValues(left, right)
+		if err != nil {
+			return nil, ne.errorf(node, "%v", err)
+		}
+		switch node.op {
+		case "<":
+			return comparison < 0, nil
+		case "<=":
+			return comparison <= 0, nil
+		case ">":
+			return comparison > 0, nil
+		default:
+			return comparison >= 0, nil
+		}
+	case "in":
+		if rv, isValid := indirectValue(reflect.ValueOf(right)); isValid &&
+			(rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) {
+			if err := ne.useOperations(rv.Len()); err != nil {
+				return nil, err
+			}
+		}
+		isIn, err := containsValue(right, left)
+		if err != nil {
+			return nil, ne.errorf(node, "%v", err)
+		}
+		return isIn, nil
+	case "..":
+		start, isStartInt := toNumber(left)
+		end, isEndInt := toNumber(right)
+		if !isStartInt || !start.isInt || !isEndInt || !end.isInt {
+			return nil, ne.errorf(node, "cannot use `..` on %T and %T", left, right)
+		}
+		// `1..0` is empty so that `1..count` can be used for a count of zero
+		if end.intValue < start.intValue-1 {
+			return nil, ne.errorf(node, "cannot count down from %d to %d using `..`, use range(%d, %d, -1) instead",
+				start.intValue, end.intValue, start.intValue, end.intValue-1)
+		}
+		return intRange(start.intValue, end.intValue+1, 1), nil
+	}
+
+	// string concatenation
+	if node.op == "+" {
+		leftStr, isLeftStr := left.(string)
+		rightStr, isRightStr := right.(string)
+		if isLeftStr || isRightStr {
+			if !isLeftStr {
+				if leftStr, err = FormatValue(left); err != nil {
+					return nil, ne.errorf(node, "%v", err)
+				}
+			}
+			if !isRightStr {
+				if rightStr, err = FormatValue(right); err != nil {
+					return nil, ne.errorf(node, "%v", err)
+				}
+			}
+			return leftStr + rightStr, nil
+		}
+	}
+
+	leftNumber, isLeftNumber := toNumber(left)
+	rightNumber, isRightNumber := toNumber(right)
+	if !isLeftNumber || !isRightNumber {
+		return nil, ne.errorf(node, "cannot use `%v` on %T and %T", node.op, left, right)
+	}
+	result, err := leftNumber.arithmetic(node.op, rightNumber)
+	if err != nil {
+		return nil, ne.errorf(node, "%v", err)
+	}
+	return result, nil
+}
+
+// number keeps integers and floats apart so that the arithmetic on
+// integers results in integers.
+type number struct {
+	isInt      bool
+	intValue   int
+	floatValue float64
+}
+
+func toNumber(value interface{}) (number, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{isInt: true, intValue: int(rv.Int())}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return number{floatValue: float64(rv.Uint())}, true
+		}
+		return number{isInt: true, intValue: int(rv.Uint())}, true
+	case reflect.Float32, reflect.Float64:
+		return number{floatValue: rv.Float()}, true
+	default:
+		return number{}, false
+	}
+}
+
+func (n number) value() interface{} {
+	if n.isInt {
+		return n.intValue
+	}
+	return n.floatValue
+}
+
+func (n number) float() float64 {
+	if n.isInt {
+		return float64(n.intValue)
+	}
+	return n.floatValue
+}
+
+func (n number) arithmetic(op string, other number) (interface{}, error) {
+	if n.isInt && other.isInt {
+		a, b := n.intValue, other.intValue
+		switch op {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		case "/", "%":
+			if b == 0 {
+				return nil, errors.New("division by zero")
+			}
+			if op == "/" {
+				return a / b, nil
+			}
+			return a % b, nil
+		}
+	}
+
+	a, b := n.float(), other.float()
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		return a / b, nil
+	case "%":
+		return math.Mod(a, b), nil
+	default:
+		return nil, fmt.Errorf("unknown operator `%v`", op)
+	}
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if isNilValue(a) || isNilValue(b) {
+		return isNilValue(a) && isNilValue(b)
+	}
+	if aNumber, isNumber := toNumber(a); isNumber {
+		if bNumber, isNumber := toNumber(b); isNumber {
+			if aNumber.isInt && bNumber.isInt {
+				return aNumber.intValue == bNumber.intValue
+			}
+			return aNumber.float() == bNumber.float()
+		}
+		return false
+	}
+	aValue, bValue := reflect.ValueOf(a), reflect.ValueOf(b)
+	if aValue.Type() == bValue.Type() && comparableValue(aValue) && comparableValue(bValue) {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// comparableValue is reflect.Type#Comparable that also checks the values
+// held by the interfaces, since comparing them panics when they hold e.g.
+// slices or maps.
+func comparableValue(rv reflect.Value) bool {
+	if !rv.Type().Comparable() {
+		return false
+	}
+	switch rv.Kind() {
+	case reflect.Interface:
+		return rv.IsNil() || comparableValue(rv.Elem())
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			if !comparableValue(rv.Field(i)) {
+				return false
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if !comparableValue(rv.Index(i)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containsValue checks if the value is an item of a slice or an array, a
+// key of a map or a substring of a string.
+func containsValue(container interface{}, value interface{}) (bool, error) {
+	if str, isString := container.(string); isString {
+		substr, isString := value.(string)
+		if !isString {
+			return false, fmt.Errorf("cannot look for %T in a string", value)
+		}
+		return strings.Contains(str, substr), nil
+	}
+
+	rv, isValid := indirectValue(reflect.ValueOf(container))
+	if !isValid {
+		return false, nil
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for itemIdx := 0; itemIdx < rv.Len(); itemIdx++ {
+			if valuesEqual(rv.Index(itemIdx).Interface(), value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		key, err := convertArg(value, rv.Type().Key())
+		if err != nil {
+			return false, nil
+		}
+		return rv.MapIndex(key).IsValid(), nil
+	default:
+		return false, fmt.Errorf("cannot look for a value in %T", container)
+	}
+}
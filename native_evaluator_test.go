@@ -0,0 +1,197 @@
+package tplinator_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+func TestNativeEvaluator(t *testing.T) {
+	params := tplinator.EvaluatorParams{
+		"count":    7,
+		"price":    2.5,
+		"name":     "Larry",
+		"nickname": nil,
+		"tags":     []string{"dog", "good"},
+		"scores":   map[string]int{"math": 90},
+		"user": &user{
+			Name:    "Larry",
+			Profile: &profile{DisplayName: "Larry the Dog"},
+			Roles:   []string{"admin"},
+		},
+		"nobody": (*user)(nil),
+		"boxes":  []struct{ V interface{} }{{[]int{1}}, {[]int{1}}, {2}},
+	}
+	evaluator := tplinator.NewNativeEvaluator().(tplinator.ConfigurableEvaluator).WithOptions(
+		tplinator.EvaluatorOptions{Funcs: tplinator.FuncMap{
+			"double": func(n int) int { return n * 2 },
+		}},
+	)
+
+	testCases := []struct {
+		name  string
+		input string
+
+		expected      interface{}
+		expectedError string
+	}{
+		{name: "int literal", input: "42", expected: 42},
+		{name: "float literal", input: "1_000.5", expected: 1000.5},
+		{name: "string literals", input: `'it\'s' + "!" + ` + "`\\n`", expected: `it's!\n`},
+		{name: "nil literal", input: "nil", expected: nil},
+		{name: "list literal", input: "[1, 'a', true,]", expected: []interface{}{1, "a", true}},
+		{name: "object literal", input: "{a: 1, 'b-c': name}", expected: map[string]interface{}{"a": 1, "b-c": "Larry"}},
+		{name: "int arithmetic", input: "count / 2 + count % 2 * 10", expected: 13},
+		{name: "float arithmetic", input: "count / 2.0", expected: 3.5},
+		{name: "mixed arithmetic", input: "count * price", expected: 17.5},
+		{name: "unary", input: "-count + +1", expected: -6},
+		{name: "precedence", input: "(1 + 2) * 3 - 4 / 2", expected: 7},
+		{name: "string concatenation", input: "name + ' has ' + count + ' toys'", expected: "Larry has 7 toys"},
+		{name: "comparison", input: "count >= 7 && price < 3 && name == 'Larry' && count != 7.5", expected: true},
+		{name: "int and float equality", input: "count == 7.0", expected: true},
+		{name: "equality of structs holding slices", input: "boxes[0] == boxes[1] && boxes[0] != boxes[2]", expected: true},
+		{name: "short circuit", input: "count > 10 && missing", expected: false},
+		{name: "not", input: "!(count > 10) || missing", expected: true},
+		{name: "ternary", input: "count > 5 ? 'many' : 'few'", expected: "many"},
+		{name: "nested ternary", input: "count > 10 ? 'a' : count > 5 ? 'b' : 'c'", expected: "b"},
+		{name: "null coalescing on nil", input: "nickname ?? name", expected: "Larry"},
+		{name: "null coalescing on missing", input: "alias ?? 'Guest'", expected: "Guest"},
+		{name: "null coalescing on value", input: "name ?? 'Guest'", expected: "Larry"},
+		{name: "optional chaining", input: "nobody?.Profile?.DisplayName ?? 'none'", expected: "none"},
+		{name: "member access", input: "user.Profile.DisplayName", expected: "Larry the Dog"},
+		{name: "slice index", input: "tags[1]", expected: "good"},
+		{name: "map index", input: "scores['math']", expected: 90},
+		{name: "string index", input: "name[0]", expected: "L"},
+		{name: "struct index", input: "user['Name']", expected: "Larry"},
+		{name: "in slice", input: "'dog' in tags && !('cat' in tags)", expected: true},
+		{name: "in map", input: "'math' in scores", expected: true},
+		{name: "in string", input: "'arr' in name", expected: true},
+		{name: "in list literal", input: "count in [1, 7]", expected: true},
+		{name: "function call", input: "double(count)", expected: 14},
+		{name: "method call", input: "user.HasRole('admin')", expected: true},
+		{name: "method call with an argument path", input: "user.Greeting(user.Profile.DisplayName)", expected: "Larry the Dog, Larry"},
+		{name: "hyphen is subtraction", input: "count-1", expected: 6},
+		{name: "missing variable", input: "alias", expectedError: "missing variable `alias`"},
+		{name: "missing map key", input: "scores['art']", expectedError: "missing variable `scores['art']`"},
+		{name: "index out of range", input: "tags[2]", expectedError: "index 2 is out of range"},
+		{name: "division by zero", input: "count / 0", expectedError: "division by zero"},
+		{name: "not a boolean", input: "count && true", expectedError: "expecting a boolean, got int"},
+		{name: "invalid operands", input: "tags - 1", expectedError: "cannot use `-` on []string and int"},
+		{name: "unknown function", input: "shout(name)", expectedError: "unknown function `shout`"},
+		{name: "fractional range bound", input: "1..price", expectedError: "cannot use `..` on int and float64"},
+		{name: "zero range step", input: "range(0, count, 0)", expectedError: "non-zero step"},
+		{name: "range step away from stop", input: "range(0, 10, -1)", expectedError: "cannot count from 0 to 10 using a step of -1"},
+		{name: "descending interval", input: "5..1", expectedError: "cannot count down from 5 to 1 using `..`, use range(5, 0, -1) instead"},
+		{name: "unexported field", input: "user.password", expectedError: "unexported"},
+		{name: "unexpected token", input: "count count", expectedError: "unexpected `count` at offset 6"},
+		{name: "unexpected end", input: "count > ", expectedError: "ends unexpectedly"},
+		{name: "unclosed string", input: "'abc", expectedError: "unclosed string literal"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := evaluator.Evaluate(tc.input, params)
+			if tc.expectedError != "" {
+				if err == nil {
+					t.Errorf("expecting an error, got `%v`", actual)
+				} else if !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("wanted an error containing `%v`, got `%v`", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("wanted `%v` (%T), got `%v` (%T)", tc.expected, tc.expected, actual, actual)
+			}
+		})
+	}
+
+	_, err := evaluator.Evaluate("alias", params)
+	var missingVarErr *tplinator.MissingVariableError
+	if !errors.As(err, &missingVarErr) {
+		t.Errorf("expecting a missing variable error, got `%v`", err)
+	}
+}
+
+type evaluatorExtDep struct {
+	evaluator tplinator.Evaluator
+}
+
+func (ed evaluatorExtDep) Get(dependencyKey tplinator.DependencyKey) interface{} {
+	if dependencyKey == tplinator.EvaluatorExtDepKey {
+		return ed.evaluator
+	}
+	return nil
+}
+
+func TestGovaluateEvaluator(t *testing.T) {
+	template := `<p go-if="[is-admin]">{{go:count / 2}} {{go:[user-name]}}</p>`
+	params := tplinator.EvaluatorParams{"is-admin": true, "user-name": "Larry", "count": 7}
+
+	tpl, err := tplinator.Tplinate(strings.NewReader(template))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual, err := tpl.RenderString(params)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<p>3.5 Larry</p>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+
+	tpl, err = tplinator.Tplinate(
+		strings.NewReader(template),
+		tplinator.EvaluatorParserOption(tplinator.NewNativeEvaluator()),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the evaluator of the template takes precedence over the dependencies
+	tpl.AddExtensionDependencies(evaluatorExtDep{evaluator: tplinator.NewGovaluateEvaluator()})
+	if _, err := tpl.RenderString(params); err == nil {
+		t.Error("expecting an error because the native evaluator does not have escaped variables")
+	}
+}
+
+func TestNativeEvaluator_OptIn(t *testing.T) {
+	template := `<p>{{go:count / 2}} {{go:user?.name ?? 'Guest'}} <i go-range="i in 1..count / 3">{{go:i}}</i></p>`
+	params := tplinator.EvaluatorParams{"count": 7, "user": nil}
+
+	testCases := []struct {
+		name      string
+		evaluator tplinator.Evaluator
+
+		expected string
+	}{
+		{
+			// the expressions that use native syntax are evaluated natively
+			name:     "default",
+			expected: `<p>3.5 Guest<i>1</i><i>2</i></p>`,
+		},
+		{
+			name:      "native",
+			evaluator: tplinator.NewNativeEvaluator(),
+			expected:  `<p>3 Guest<i>1</i><i>2</i></p>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(strings.NewReader(template))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.evaluator != nil {
+				tpl.AddExtensionDependencies(evaluatorExtDep{evaluator: tc.evaluator})
+			}
+			actual, err := tpl.RenderString(params)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
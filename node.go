@@ -2,6 +2,7 @@ package tplinator
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/alediaferia/stackgo"
 	"golang.org/x/net/html"
@@ -12,6 +13,7 @@ type Node struct {
 	Type html.NodeType
 
 	isSelfClosing bool
+	position      Position
 
 	attributes []Attribute
 	extensions []Extension
@@ -53,7 +55,9 @@ func CopyNode(node *Node) *Node {
 			Data:          n.Data,
 			Type:          n.Type,
 			isSelfClosing: n.isSelfClosing,
+			position:      n.position,
 			contextParams: n.contextParams,
+			parentECS:     n.parentECS,
 		}
 		for _, attr := range n.attributes {
 			nodeCopy.attributes = append(nodeCopy.attributes, attr)
@@ -74,7 +78,10 @@ func CopyNode(node *Node) *Node {
 		newParent *Node
 	}
 
+	// the copy is not attached to the parent of the node that was
+	// copied so it needs to keep the node's scope some other way
 	nodeCopy := copyNode(node)
+	nodeCopy.parentECS = node.scopeSource()
 
 	copierStack := stackgo.NewStack()
 	copierStack.Push(root{
@@ -123,6 +130,10 @@ func CopyNode(node *Node) *Node {
 	return nodeCopy
 }
 
+func (n Node) Position() Position {
+	return n.position
+}
+
 func (n Node) ContextParams() EvaluatorParams {
 	return n.contextParams
 }
@@ -134,28 +145,17 @@ func (n *Node) SetContextParams(contextParams EvaluatorParams) {
 func (n *Node) GetContextParams() []EvaluatorParams {
 	var evaluatorParams []EvaluatorParams
 
-	if n.parentECS != nil {
-		if n.contextParams != nil {
-			evaluatorParams = append(evaluatorParams, n.contextParams)
-		}
-		if parentCP := n.parentECS.GetContextParams(); len(parentCP) > 0 {
-			evaluatorParams = append(evaluatorParams, parentCP...)
-		}
-		return evaluatorParams
-	}
-
-	epStack := stackgo.NewStack()
-	epStack.Push(n)
-
-	for epStack.Top() != nil {
-		currentNode := epStack.Pop().(*Node)
+	// a node that has a parent evaluator context source (e.g. a copy made
+	// by an extension) continues its scope chain there instead of on its
+	// parent node
+	for currentNode := n; currentNode != nil; currentNode = currentNode.parent {
 		if currentNode.contextParams != nil {
 			evaluatorParams = append(
 				evaluatorParams, currentNode.contextParams,
 			)
 		}
-		if parentNode := currentNode.Parent(); parentNode != nil {
-			epStack.Push(parentNode)
+		if currentNode.parentECS != nil {
+			return append(evaluatorParams, currentNode.parentECS.GetContextParams()...)
 		}
 	}
 
@@ -166,6 +166,66 @@ func (n *Node) SetParentEvaluatorContextSource(ecs EvaluatorContextSource) {
 	n.parentECS = ecs
 }
 
+// scopeSource returns where the scope of the node's children continues.
+func (n *Node) scopeSource() EvaluatorContextSource {
+	if n.parent != nil {
+		return n.parent
+	}
+	if n.parentECS != nil {
+		return n.parentECS
+	}
+	return nil
+}
+
+// Scope merges the params of the node's scope chain into a single
+// EvaluatorParams. The params of the innermost scope shadow the params of
+// the outer scopes and the root params are the outermost scope.
+func (n *Node) Scope(params EvaluatorParams) EvaluatorParams {
+	return scopeParams(n, params)
+}
+
+func scopeParams(ecs EvaluatorContextSource, params EvaluatorParams) EvaluatorParams {
+	return newScopeChain(ecs, params).merge()
+}
+
+// scopeChain is the params of a scope chain, from the innermost scope to
+// the root params. The built-in evaluators look up the variables through
+// the chain so that it is not merged for every evaluation.
+type scopeChain []EvaluatorParams
+
+func newScopeChain(ecs EvaluatorContextSource, params EvaluatorParams) scopeChain {
+	return append(scopeChain(ecs.GetContextParams()), params)
+}
+
+func (sc scopeChain) lookup(name string) (interface{}, bool) {
+	for _, params := range sc {
+		if value, hasValue := params[name]; hasValue {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// merge merges the params of the chain into a single EvaluatorParams for
+// the evaluators that do not look up variables through the chain.
+func (sc scopeChain) merge() EvaluatorParams {
+	if len(sc) == 1 {
+		return sc[0]
+	}
+
+	size := 0
+	for _, params := range sc {
+		size += len(params)
+	}
+	scope := make(EvaluatorParams, size)
+	for i := len(sc) - 1; i >= 0; i-- {
+		for key, value := range sc[i] {
+			scope[key] = value
+		}
+	}
+	return scope
+}
+
 func (n Node) Parent() *Node {
 	return n.parent
 }
@@ -186,6 +246,11 @@ func (n Node) NextSibling() *Node {
 	return n.nextSibling
 }
 
+// VirtualElementName is the name of the element that only its children are
+// rendered of, so that directives like `go-if` and `go-range` can be used
+// on a group of siblings or on a text without wrapping them in an element.
+const VirtualElementName = "go-template"
+
 func (n Node) Tags() (string, string) {
 	switch n.Type {
 	case html.DoctypeNode:
@@ -193,6 +258,9 @@ func (n Node) Tags() (string, string) {
 	case html.TextNode:
 		return n.Data, ""
 	case html.ElementNode:
+		if n.Data == VirtualElementName {
+			return "", ""
+		}
 		startTag := "<" + n.Data
 		for _, attr := range n.attributes {
 			startTag += " " + attr.String()
@@ -386,3 +454,31 @@ func (a Attribute) String() string {
 	}
 	return a.Key + "=\"" + a.Value + "\""
 }
+
+// Position is where a node starts on the template. Both the line and the
+// column start at 1. The nodes that were not created by a parser have a
+// zero Position.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+func (p Position) advance(text string) Position {
+	for _, r := range text {
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
@@ -1,8 +1,12 @@
 package tplinator
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 
 	"golang.org/x/net/html"
@@ -12,6 +16,22 @@ type Extension interface {
 	Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error)
 }
 
+// invalidDirectiveExtension is added by the node processors to the
+// elements whose directives cannot be used where they are, since node
+// processors cannot fail. The parser fails with its error.
+type invalidDirectiveExtension struct {
+	err error
+}
+
+func (ide *invalidDirectiveExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
+	return nil, nil, ide.err
+}
+
+func addInvalidDirective(node *Node, format string, args ...interface{}) {
+	err := fmt.Errorf("%v: %v", node.Position(), fmt.Sprintf(format, args...))
+	node.AddExtension(&invalidDirectiveExtension{err: err})
+}
+
 type conditionalExtensionCondition struct {
 	node                  *Node
 	conditionalExpression string
@@ -23,21 +43,58 @@ type ConditionalExtension struct {
 }
 
 func (ce *ConditionalExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
-	for _, condition := range ce.conditions {
-		evaluator := dependencies.Get(EvaluatorExtDepKey).(Evaluator)
-		hasResult, result, err := TryEvaluateBoolUsingContext(
-			node, evaluator, condition.conditionalExpression,
+	for conditionIdx, condition := range ce.conditions {
+		result, err := evaluateBoolPipeline(
+			node, dependencies, condition.conditionalExpression, params,
 		)
-		if !hasResult {
-			result, err = evaluator.EvaluateBool(condition.conditionalExpression, params)
-		}
 		if err != nil {
 			return nil, nil, err
 		} else if result {
-			return condition.node, nil, nil
+			// the first condition belongs to the node itself, which might
+			// be a copy made by another extension (e.g. RangeExtension)
+			if conditionIdx == 0 {
+				return node, nil, nil
+			}
+			return branchNode(node, condition.node), nil, nil
 		}
 	}
-	return ce.elseNode, nil, nil
+	if ce.elseNode != nil {
+		return branchNode(node, ce.elseNode), nil, nil
+	}
+	return nil, nil, nil
+}
+
+// branchNode copies a sibling that was removed from the tree by a node
+// processor so that it can take the place of the node using the node's
+// scope. There's no need to copy it if the node's scope only consists of
+// the root params.
+func branchNode(node *Node, branch *Node) *Node {
+	if len(node.GetContextParams()) == 0 {
+		return branch
+	}
+	branchCopy := CopyNode(branch)
+	branchCopy.SetParentEvaluatorContextSource(node.scopeSource())
+	return branchCopy
+}
+
+// applyBranch applies the extensions of a branch that takes the place of
+// the node and returns what it is rendered as.
+func applyBranch(node *Node, branch *Node, dependencies ExtensionDependencies, params EvaluatorParams) ([]*Node, error) {
+	branchCopy, siblings, err := branchNode(node, branch).ApplyExtensions(dependencies, params)
+	if err != nil {
+		return nil, err
+	} else if branchCopy != nil {
+		siblings = append(siblings, branchCopy)
+	}
+	return siblings, nil
+}
+
+func (ce *ConditionalExtension) Expressions() []string {
+	expressions := make([]string, len(ce.conditions))
+	for conditionIdx, condition := range ce.conditions {
+		expressions[conditionIdx] = condition.conditionalExpression
+	}
+	return expressions
 }
 
 func (ce *ConditionalExtension) addCondition(condition string, node *Node) {
@@ -49,13 +106,12 @@ func (ce *ConditionalExtension) addCondition(condition string, node *Node) {
 
 func ConditionalExtensionNodeProcessor(node *Node) {
 	if hasAttribute, _, ifCondition := node.HasAttribute("go-if"); hasAttribute {
-		condBranchSiblings := make([]*Node, 0)
 		conditionalExtension := &ConditionalExtension{}
 
 		node.RemoveAttribute("go-if")
 		conditionalExtension.addCondition(ifCondition, node)
 
-		node.NextSiblings(func(sibling *Node) bool {
+		consumeBranchSiblings(node, func(sibling *Node) (bool, bool) {
 			hasElifAttr, _, elifCondition := sibling.HasAttribute("go-elif")
 			hasElseIfAttr, _, elseIfCondition := sibling.HasAttribute("go-else-if")
 
@@ -68,23 +124,31 @@ func ConditionalExtensionNodeProcessor(node *Node) {
 			} else if hasElseAttr, _, _ := sibling.HasAttribute("go-else"); hasElseAttr {
 				sibling.RemoveAttribute("go-else")
 				conditionalExtension.elseNode = sibling
-
-				condBranchSiblings = append(condBranchSiblings, sibling)
-
-				return false
+				return true, true
 			} else {
-				return false
+				return false, true
 			}
-
-			condBranchSiblings = append(condBranchSiblings, sibling)
-			return true
+			return true, false
 		})
 
 		node.AddExtension(conditionalExtension)
+	}
+}
 
-		for _, condBranchSibling := range condBranchSiblings {
-			condBranchSibling.Parent().RemoveChild(condBranchSibling)
+// consumeBranchSiblings passes the next siblings of the node to branchFunc
+// until it returns isLast and then removes the siblings that it accepted as
+// branches from the tree, since they are rendered in place of the node.
+func consumeBranchSiblings(node *Node, branchFunc func(sibling *Node) (isBranch bool, isLast bool)) {
+	var branchSiblings []*Node
+	node.NextSiblings(func(sibling *Node) bool {
+		isBranch, isLast := branchFunc(sibling)
+		if isBranch {
+			branchSiblings = append(branchSiblings, sibling)
 		}
+		return !isLast
+	})
+	for _, branchSibling := range branchSiblings {
+		branchSibling.Parent().RemoveChild(branchSibling)
 	}
 }
 
@@ -96,6 +160,9 @@ type conditionalClassExtensionCondition struct {
 type ConditionalClassExtension struct {
 	originalClasses    []string
 	conditionalClasses []conditionalClassExtensionCondition
+	// classExpression is the expression of the `go-class` attribute, which
+	// evaluates to a string, a list or a map of class names to booleans.
+	classExpression string
 }
 
 func (ce *ConditionalClassExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
@@ -104,30 +171,108 @@ func (ce *ConditionalClassExtension) Apply(node *Node, dependencies ExtensionDep
 
 	appliedClasses = append(appliedClasses, ce.originalClasses...)
 	for _, conditionalClass := range ce.conditionalClasses {
-		evaluator := dependencies.Get(EvaluatorExtDepKey).(Evaluator)
-		hasResult, result, err := TryEvaluateBoolUsingContext(
-			node, evaluator, conditionalClass.conditionalExpression,
+		result, err := evaluateBoolPipeline(
+			node, dependencies, conditionalClass.conditionalExpression, params,
 		)
-		if !hasResult {
-			result, err = evaluator.EvaluateBool(conditionalClass.conditionalExpression, params)
-		}
 		if err != nil {
 			return nil, nil, err
 		} else if result {
 			appliedClasses = append(appliedClasses, conditionalClass.className)
 		}
 	}
+	if ce.classExpression != "" {
+		result, err := evaluatePipeline(node, dependencies, ce.classExpression, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		if appliedClasses, err = appendClassNames(appliedClasses, result); err != nil {
+			return nil, nil, fmt.Errorf("class ext: `%v`: %v", ce.classExpression, err)
+		}
+	}
 	if len(appliedClasses) > 0 {
-		copyNode.AddAttribute("class", strings.Join(appliedClasses, " "))
+		copyNode.AddAttribute("class", strings.Join(uniqueClassNames(appliedClasses), " "))
 	}
 	return copyNode, nil, nil
 }
 
+func (ce *ConditionalClassExtension) Expressions() []string {
+	expressions := make([]string, len(ce.conditionalClasses))
+	for conditionIdx, conditionalClass := range ce.conditionalClasses {
+		expressions[conditionIdx] = conditionalClass.conditionalExpression
+	}
+	if ce.classExpression != "" {
+		expressions = append(expressions, ce.classExpression)
+	}
+	return expressions
+}
+
+// appendClassNames appends the class names of the value of a `go-class`
+// expression. The names of a map are appended in order if their values are
+// true, and the items of a list can be any value that is accepted here.
+func appendClassNames(classNames []string, value interface{}) ([]string, error) {
+	switch value := value.(type) {
+	case nil, missingValue:
+		return classNames, nil
+	case string:
+		return append(classNames, strings.Fields(value)...), nil
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		var err error
+		for itemIdx := 0; itemIdx < reflectValue.Len(); itemIdx++ {
+			if classNames, err = appendClassNames(classNames, reflectValue.Index(itemIdx).Interface()); err != nil {
+				return nil, err
+			}
+		}
+		return classNames, nil
+	case reflect.Map:
+		if reflectValue.Type().Key().Kind() != reflect.String {
+			break
+		}
+		names := make([]string, 0, reflectValue.Len())
+		for _, key := range reflectValue.MapKeys() {
+			names = append(names, key.String())
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			isApplied := reflectValue.MapIndex(reflect.ValueOf(name).Convert(reflectValue.Type().Key())).Interface()
+			if _, isMissing := isApplied.(missingValue); isMissing {
+				continue
+			}
+			boolValue, isBool := isApplied.(bool)
+			if !isBool {
+				return nil, fmt.Errorf("expecting a bool for the class `%v`, got %T", name, isApplied)
+			} else if boolValue {
+				classNames = append(classNames, strings.Fields(name)...)
+			}
+		}
+		return classNames, nil
+	}
+	return nil, fmt.Errorf("expecting a string, a list or a map of class names, got %T", value)
+}
+
+// uniqueClassNames removes the class names that were already used from the
+// slice, keeping the order of their first usages.
+func uniqueClassNames(classNames []string) []string {
+	isUsed := make(map[string]bool, len(classNames))
+	uniqueNames := classNames[:0:0]
+	for _, className := range classNames {
+		if !isUsed[className] {
+			isUsed[className] = true
+			uniqueNames = append(uniqueNames, className)
+		}
+	}
+	return uniqueNames
+}
+
 func ConditionalClassExtensionNodeProcessor(node *Node) {
 	ifClassAttrs := node.HasAttributes(func(attr Attribute) bool {
 		return strings.HasPrefix(attr.Key, "go-if-class-")
 	})
-	if len(ifClassAttrs) > 0 {
+	hasClassExpression, _, classExpression := node.HasAttribute("go-class")
+	if len(ifClassAttrs) > 0 || hasClassExpression {
 		conditionalClassExtension := &ConditionalClassExtension{}
 
 		hasClass, _, class := node.HasAttribute("class")
@@ -150,6 +295,10 @@ func ConditionalClassExtensionNodeProcessor(node *Node) {
 				node.RemoveAttribute(ifClassAttr.Key)
 			}
 		}
+		if hasClassExpression {
+			conditionalClassExtension.classExpression = strings.TrimSpace(classExpression)
+			node.RemoveAttribute("go-class")
+		}
 
 		node.AddExtension(conditionalClassExtension)
 	}
@@ -157,6 +306,22 @@ func ConditionalClassExtensionNodeProcessor(node *Node) {
 
 type RangeExtension struct {
 	sourceVarName string
+	// alias is the name of the items in the scope of the copies of the
+	// node. The keys of the items are merged into the scope if it is empty.
+	// If there's a second alias, the aliases are the key and the value of
+	// the items of keyed sources and the value and the index of the items
+	// of the other sources.
+	alias       string
+	secondAlias string
+	// emptyNode is the sibling that is rendered in place of the node if
+	// the source has no items.
+	emptyNode *Node
+	// isRepeat is set if the source is a count instead, in which case the
+	// items only add the `$loop` to the scope.
+	isRepeat bool
+	// modifiers are the calls at the end of the declaration that change
+	// which items are rendered and in which order, e.g. `| chunk(3)`.
+	modifiers []pipelineFilterCall
 
 	isApplyingOnNewNodes bool
 }
@@ -166,17 +331,69 @@ func (re *RangeExtension) Apply(node *Node, dependencies ExtensionDependencies,
 		return node, nil, nil
 	}
 
-	evaluator := dependencies.Get(EvaluatorExtDepKey).(Evaluator)
-	hasResult, result, err := TryEvaluateUsingContext(node, evaluator, re.sourceVarName)
-	if !hasResult {
-		result, err = evaluator.Evaluate(re.sourceVarName, params)
-	}
+	result, err := evaluatePipeline(node, dependencies, re.sourceVarName, params)
 	if err != nil {
 		return nil, nil, err
 	}
-	rangeEvalParams, isRangeEvalParam := result.(RangeEvaluatorParams)
-	if !isRangeEvalParam {
-		return nil, nil, fmt.Errorf("the type of `%s` is not RangeEvaluatorParams", re.sourceVarName)
+	if re.isRepeat {
+		// govaluate only has float64 numbers, so whole floats are counts too
+		count, isNumber := toNumber(result)
+		if isNumber && !count.isInt && count.floatValue == math.Trunc(count.floatValue) {
+			count = number{isInt: true, intValue: int(count.floatValue)}
+		}
+		if !isNumber || !count.isInt {
+			return nil, nil, fmt.Errorf("range ext: `%s`: expecting a count, got %T", re.sourceVarName, result)
+		} else if count.intValue < 0 {
+			return nil, nil, fmt.Errorf("range ext: `%s`: expecting a count that is not negative, got %d", re.sourceVarName, count.intValue)
+		}
+		result = intRange(0, count.intValue, 1)
+	}
+
+	budget, hasBudget := dependencies.Get(renderBudgetExtDepKey).(*renderBudget)
+	var items []rangeItem
+	err = forEachRangeItem(result, func(item rangeItem) error {
+		if hasBudget {
+			if err := budget.useRangeIterations(node, 1); err != nil {
+				return err
+			}
+		}
+		items = append(items, item)
+		return nil
+	})
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return nil, nil, err
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("range ext: `%s`: %v", re.sourceVarName, err)
+	}
+	if len(re.modifiers) > 0 {
+		valueType := interfaceType
+		if result != nil {
+			if _, sourceValueType, _, err := rangeItemTypes(reflect.TypeOf(result)); err == nil && sourceValueType != nil {
+				valueType = sourceValueType
+			}
+		}
+		items, err = re.applyModifiers(node, dependencies, params, items, valueType)
+		if errors.As(err, &limitErr) {
+			return nil, nil, err
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("range ext: `%s`: %v", re.sourceVarName, err)
+		}
+	}
+	if len(items) == 0 && re.emptyNode != nil {
+		emptyNodes, err := applyBranch(node, re.emptyNode, dependencies, params)
+		return nil, emptyNodes, err
+	}
+
+	parentLoop := enclosingRangeLoop(node)
+	rangeEvalParams := make(RangeEvaluatorParams, len(items))
+	for itemIdx, item := range items {
+		itemParams, err := re.itemParams(item)
+		if err != nil {
+			return nil, nil, fmt.Errorf("range ext: `%s`: %v", re.sourceVarName, err)
+		}
+		itemParams[LoopParam] = newRangeLoop(itemIdx, len(items), parentLoop)
+		rangeEvalParams[itemIdx] = itemParams
 	}
 
 	re.isApplyingOnNewNodes = true
@@ -186,13 +403,12 @@ func (re *RangeExtension) Apply(node *Node, dependencies ExtensionDependencies,
 		nodeCopy := CopyNode(node)
 		nodeCopy.SetContextParams(rangeEvalParam)
 
-		if parent := node.Parent(); parent != nil {
-			nodeCopy.SetParentEvaluatorContextSource(parent)
-		}
+		nodeCopy.SetParentEvaluatorContextSource(node.scopeSource())
 
 		// ignore new siblings produced by this Node#ApplyExtensions func call
 		newNodeCopy, _, err := nodeCopy.ApplyExtensions(dependencies, params)
 		if err != nil {
+			re.isApplyingOnNewNodes = false
 			return nil, nil, err
 		} else if newNodeCopy != nil {
 			newNodes = append(newNodes, newNodeCopy)
@@ -204,6 +420,52 @@ func (re *RangeExtension) Apply(node *Node, dependencies ExtensionDependencies,
 	return nil, newNodes, nil
 }
 
+// itemParams returns the params that the item adds to the scope of its
+// copy of the node. They are always a new EvaluatorParams so that the
+// `$loop` can be added without changing the item.
+func (re *RangeExtension) itemParams(item rangeItem) (EvaluatorParams, error) {
+	switch {
+	case re.isRepeat:
+		return EvaluatorParams{}, nil
+	case re.secondAlias != "" && item.keyed:
+		return EvaluatorParams{re.alias: item.key, re.secondAlias: item.value}, nil
+	case re.secondAlias != "":
+		return EvaluatorParams{re.alias: item.value, re.secondAlias: item.key}, nil
+	case re.alias != "":
+		return EvaluatorParams{re.alias: item.value}, nil
+	}
+
+	valueParams, err := ValueParams(item.value)
+	if err != nil {
+		return nil, fmt.Errorf("item %v: %v, use `item in %v` to name the items instead",
+			item.key, err, re.sourceVarName)
+	}
+	itemParams := make(EvaluatorParams, len(valueParams)+1)
+	for key, value := range valueParams {
+		itemParams[key] = value
+	}
+	return itemParams, nil
+}
+
+// enclosingRangeLoop returns the `$loop` of the innermost range that the
+// node is in.
+func enclosingRangeLoop(node *Node) *RangeLoop {
+	for _, contextParams := range node.GetContextParams() {
+		if loop, isLoop := contextParams[LoopParam].(*RangeLoop); isLoop {
+			return loop
+		}
+	}
+	return nil
+}
+
+func (re *RangeExtension) Expressions() []string {
+	expressions := []string{re.sourceVarName}
+	for _, modifier := range re.modifiers {
+		expressions = append(expressions, modifier.args...)
+	}
+	return expressions
+}
+
 type RangeEvaluatorParams []EvaluatorParams
 
 func RangeParams(params ...EvaluatorParams) RangeEvaluatorParams {
@@ -211,16 +473,45 @@ func RangeParams(params ...EvaluatorParams) RangeEvaluatorParams {
 }
 
 func RangeExtensionNodeProcessor(node *Node) {
+	hasRange, _, _ := node.HasAttribute("go-range")
+	hasRepeat, _, _ := node.HasAttribute("go-repeat")
+	if hasRange && hasRepeat {
+		addInvalidDirective(node, "`go-range` cannot be used with `go-repeat`")
+		return
+	}
+
+	var rangeExtension *RangeExtension
 	if hasRange, _, rangeDeclaration := node.HasAttribute("go-range"); hasRange {
-		rangeExtension := &RangeExtension{
-			sourceVarName: rangeDeclaration,
+		alias, secondAlias, source := parseRangeDeclaration(rangeDeclaration)
+		source, modifiers := splitRangeModifiers(source)
+		rangeExtension = &RangeExtension{
+			sourceVarName: source,
+			alias:         alias,
+			secondAlias:   secondAlias,
+			modifiers:     modifiers,
 		}
-		node.AddExtension(rangeExtension)
 		node.RemoveAttribute("go-range")
+	} else if hasRepeat, _, count := node.HasAttribute("go-repeat"); hasRepeat {
+		rangeExtension = &RangeExtension{sourceVarName: strings.TrimSpace(count), isRepeat: true}
+		node.RemoveAttribute("go-repeat")
+	} else {
+		return
 	}
+	node.AddExtension(rangeExtension)
+
+	consumeBranchSiblings(node, func(sibling *Node) (bool, bool) {
+		for _, emptyAttr := range []string{"go-range-empty", "go-else"} {
+			if hasEmptyAttr, _, _ := sibling.HasAttribute(emptyAttr); hasEmptyAttr {
+				sibling.RemoveAttribute(emptyAttr)
+				rangeExtension.emptyNode = sibling
+				return true, true
+			}
+		}
+		return false, true
+	})
 }
 
-var stringInterpolationMarkerRegex = regexp.MustCompile("{{go:[a-zA-Z]+[a-zA-Z\\d\\.]*[a-zA-Z\\d]*}}")
+var stringInterpolationMarkerRegex = regexp.MustCompile("{{go:(.+?)}}")
 
 type strInterpMarker struct {
 	marker string
@@ -237,27 +528,39 @@ type AttrStringInterpExtension struct {
 }
 
 func (asie AttrStringInterpExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
-	evaluator := dependencies.Get(EvaluatorExtDepKey).(Evaluator)
+	// interpolate on a copy so that the markers stay on the template
+	copyNode := CopyNode(node)
 
 	for _, marker := range asie.markers {
-		hasAttr, _, attrVal := node.HasAttribute(marker.attributeKey)
+		hasAttr, _, attrVal := copyNode.HasAttribute(marker.attributeKey)
 		if !hasAttr {
 			return nil, nil, fmt.Errorf("attr string interp ext: assertion error. cannot find attr `%v`", marker.attributeKey)
 		}
 		for _, marker := range marker.markers {
-			hasResult, result, err := TryEvaluateStringUsingContext(node, evaluator, marker.key)
-			if !hasResult {
-				result, err = evaluator.EvaluateString(marker.key, params)
+			result, err := evaluatePipeline(node, dependencies, marker.key, params)
+			if err != nil {
+				return nil, nil, fmt.Errorf("attr string interp ext: %w", err)
 			}
+			formattedResult, err := formatValue(dependencies, result)
 			if err != nil {
-				return nil, nil, fmt.Errorf("attr string interp ext: %v", err)
+				return nil, nil, fmt.Errorf("attr string interp ext: `%v`: %v", marker.key, err)
 			}
-			attrVal = strings.Replace(attrVal, marker.marker, result, 1)
+			attrVal = strings.Replace(attrVal, marker.marker, formattedResult, 1)
 		}
-		node.ReplaceAttribute(marker.attributeKey, attrVal)
+		copyNode.ReplaceAttribute(marker.attributeKey, attrVal)
 	}
 
-	return node, nil, nil
+	return copyNode, nil, nil
+}
+
+func (asie AttrStringInterpExtension) Expressions() []string {
+	var expressions []string
+	for _, marker := range asie.markers {
+		for _, marker := range marker.markers {
+			expressions = append(expressions, marker.key)
+		}
+	}
+	return expressions
 }
 
 type TextStringInterpExtension struct {
@@ -265,20 +568,88 @@ type TextStringInterpExtension struct {
 }
 
 func (tsie TextStringInterpExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
-	evaluator := dependencies.Get(EvaluatorExtDepKey).(Evaluator)
+	// interpolate on a copy so that the markers stay on the template
+	copyNode := CopyNode(node)
 
 	for _, marker := range tsie.markers {
-		hasResult, result, err := TryEvaluateStringUsingContext(node, evaluator, marker.key)
-		if !hasResult {
-			result, err = evaluator.EvaluateString(marker.key, params)
+		result, err := evaluatePipeline(node, dependencies, marker.key, params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("text string interp ext: %w", err)
 		}
+		formattedResult, err := formatValue(dependencies, result)
 		if err != nil {
-			return nil, nil, fmt.Errorf("text string interp ext: %v", err)
+			return nil, nil, fmt.Errorf("text string interp ext: `%v`: %v", marker.key, err)
+		}
+		copyNode.Data = strings.Replace(copyNode.Data, marker.marker, formattedResult, 1)
+	}
+
+	return copyNode, nil, nil
+}
+
+func (tsie TextStringInterpExtension) Expressions() []string {
+	expressions := make([]string, len(tsie.markers))
+	for markerIdx, marker := range tsie.markers {
+		expressions[markerIdx] = marker.key
+	}
+	return expressions
+}
+
+// TextExtension replaces the children of an element that has a `go-text`
+// attribute with the escaped value of its expression, so that the children
+// of the element on the template can be used as a placeholder.
+type TextExtension struct {
+	expression string
+}
+
+func (te *TextExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
+	result, err := evaluatePipeline(node, dependencies, te.expression, params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("text ext: %w", err)
+	}
+	formattedResult, err := formatValue(dependencies, result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("text ext: `%v`: %v", te.expression, err)
+	}
+
+	copyNode := CopyNode(node)
+	for child := copyNode.FirstChild(); child != nil; child = copyNode.FirstChild() {
+		copyNode.RemoveChild(child)
+	}
+	copyNode.AppendChild(&Node{
+		Type:     html.TextNode,
+		Data:     html.EscapeString(formattedResult),
+		position: node.position,
+	})
+	return copyNode, nil, nil
+}
+
+func (te *TextExtension) Expressions() []string {
+	return []string{te.expression}
+}
+
+// TextExtensionNodeProcessor adds the TextExtension to the elements that
+// have a `go-text` attribute. It cannot be used with `go-html`, which also
+// replaces the children of the element.
+func TextExtensionNodeProcessor(node *Node) {
+	if hasText, _, expression := node.HasAttribute("go-text"); hasText {
+		if hasHTML, _, _ := node.HasAttribute("go-html"); hasHTML {
+			addInvalidDirective(node, "`go-text` cannot be used with `go-html`")
+			return
 		}
-		node.Data = strings.Replace(node.Data, marker.marker, result, 1)
+		node.AddExtension(&TextExtension{expression: strings.TrimSpace(expression)})
+		node.RemoveAttribute("go-text")
 	}
+}
 
-	return node, nil, nil
+func formatValue(dependencies ExtensionDependencies, value interface{}) (string, error) {
+	if missing, isMissing := value.(missingValue); isMissing {
+		return missing.String(), nil
+	}
+	valueFormatter, isValueFormatter := dependencies.Get(ValueFormatterExtDepKey).(ValueFormatter)
+	if !isValueFormatter {
+		valueFormatter = DefaultValueFormatter{}
+	}
+	return valueFormatter.FormatValue(value)
 }
 
 func StringInterpolationNodeProcessor(node *Node) {
@@ -293,7 +664,7 @@ func StringInterpolationNodeProcessor(node *Node) {
 				}
 				for _, marker := range matches {
 					key := strings.TrimPrefix(marker, "{{go:")
-					key = strings.TrimSuffix(key, "}}")
+					key = strings.TrimSpace(strings.TrimSuffix(key, "}}"))
 					attrMarker.markers = append(attrMarker.markers, strInterpMarker{
 						marker: marker,
 						key:    key,
@@ -313,7 +684,7 @@ func StringInterpolationNodeProcessor(node *Node) {
 			tsiExt := &TextStringInterpExtension{}
 			for _, marker := range matches {
 				key := strings.TrimPrefix(marker, "{{go:")
-				key = strings.TrimSuffix(key, "}}")
+				key = strings.TrimSpace(strings.TrimSuffix(key, "}}"))
 				tsiExt.markers = append(tsiExt.markers, strInterpMarker{
 					marker: marker,
 					key:    key,
@@ -3,7 +3,15 @@ package tplinator
 type DependencyKey string
 
 const (
-	EvaluatorExtDepKey DependencyKey = "evaluator"
+	EvaluatorExtDepKey      DependencyKey = "evaluator"
+	ValueFormatterExtDepKey DependencyKey = "valueFormatter"
+	FiltersExtDepKey        DependencyKey = "filters"
+	FuncsExtDepKey          DependencyKey = "funcs"
+	MissingKeyExtDepKey     DependencyKey = "missingKey"
+	LimitsExtDepKey         DependencyKey = "limits"
+	CatalogExtDepKey        DependencyKey = "catalog"
+	LocaleExtDepKey         DependencyKey = "locale"
+	DiagnosticsExtDepKey    DependencyKey = "diagnostics"
 )
 
 type ExtensionDependencies interface {
@@ -13,24 +21,100 @@ type ExtensionDependencies interface {
 type compoundExtensionDependencies struct {
 	extDeps       []ExtensionDependencies
 	defaultExtDep ExtensionDependencies
+
+	funcs      FuncMap
+	evaluator  Evaluator
+	missingKey MissingKeyPolicy
+	limits     *Limits
 }
 
 func (ed *compoundExtensionDependencies) Get(dependencyKey DependencyKey) interface{} {
+	switch dependencyKey {
+	case FiltersExtDepKey:
+		return ed.getFilters()
+	case FuncsExtDepKey:
+		return ed.getFuncs()
+	case EvaluatorExtDepKey:
+		// the evaluator that was set on the template takes precedence
+		if ed.evaluator != nil {
+			return ed.evaluator
+		}
+	case MissingKeyExtDepKey:
+		// the policy that was set on the template takes precedence
+		if ed.missingKey != 0 {
+			return ed.missingKey
+		}
+	case LimitsExtDepKey:
+		if ed.limits != nil {
+			return *ed.limits
+		}
+	}
 	for _, extDep := range ed.extDeps {
 		if dep := extDep.Get(dependencyKey); dep != nil {
 			return dep
 		}
 	}
+	if ed.defaultExtDep == nil {
+		return nil
+	}
 	return ed.defaultExtDep.Get(dependencyKey)
 }
 
+// getAll returns the dependency of every ExtensionDependencies which has
+// one for the given key. The dependencies of the ExtensionDependencies
+// that were added first come first and the default one comes last.
+func (ed *compoundExtensionDependencies) getAll(dependencyKey DependencyKey) []interface{} {
+	var deps []interface{}
+	for _, extDep := range ed.extDeps {
+		if dep := extDep.Get(dependencyKey); dep != nil {
+			deps = append(deps, dep)
+		}
+	}
+	if ed.defaultExtDep != nil {
+		if dep := ed.defaultExtDep.Get(dependencyKey); dep != nil {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// getFilters merges the filters of every dependency so that registering
+// a filter does not hide the default ones.
+func (ed *compoundExtensionDependencies) getFilters() FilterMap {
+	var filterMaps []FilterMap
+	for _, dep := range ed.getAll(FiltersExtDepKey) {
+		if filterMap, isFilterMap := dep.(FilterMap); isFilterMap {
+			filterMaps = append(filterMaps, filterMap)
+		}
+	}
+	return mergeFilterMaps(filterMaps...)
+}
+
+// getFuncs merges the functions of every dependency. The functions that
+// were added to the template take precedence over all of them.
+func (ed *compoundExtensionDependencies) getFuncs() FuncMap {
+	funcMaps := []FuncMap{ed.funcs}
+	for _, dep := range ed.getAll(FuncsExtDepKey) {
+		if funcMap, isFuncMap := dep.(FuncMap); isFuncMap {
+			funcMaps = append(funcMaps, funcMap)
+		}
+	}
+	return mergeFuncMaps(funcMaps...)
+}
+
 type DefaultExtensionDependencies struct {
-	evaluator Evaluator
+	evaluator      Evaluator
+	valueFormatter ValueFormatter
+	filters        FilterMap
+	missingKey     MissingKeyPolicy
 }
 
 func NewDefaultExtensionDependencies() ExtensionDependencies {
 	return &DefaultExtensionDependencies{
-		evaluator: &govaluator{},
+		evaluator:      NewGovaluateEvaluator(),
+		valueFormatter: DefaultValueFormatter{},
+		filters:        DefaultFilters(),
+		missingKey:     MissingKeyError,
 	}
 }
 
@@ -38,6 +122,12 @@ func (ed *DefaultExtensionDependencies) Get(dependencyKey DependencyKey) interfa
 	switch dependencyKey {
 	case EvaluatorExtDepKey:
 		return ed.evaluator
+	case ValueFormatterExtDepKey:
+		return ed.valueFormatter
+	case FiltersExtDepKey:
+		return ed.filters
+	case MissingKeyExtDepKey:
+		return ed.missingKey
 	default:
 		return nil
 	}
@@ -1,6 +1,7 @@
 package tplinator_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/bmdelacruz/tplinator"
@@ -370,3 +371,857 @@ func TestNodeExtension_StringInterpolation(t *testing.T) {
 		t.Errorf("newANode should have an href attribute")
 	}
 }
+
+func TestNodeExtension_ScopeChain(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		params   tplinator.EvaluatorParams
+
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "item and root variables in one expression",
+			template: `<ul><li go-range="pets">{{go:greeting + ', ' + name}}</li></ul>`,
+			params: tplinator.EvaluatorParams{
+				"greeting": "Hi",
+				"pets": tplinator.RangeParams(
+					tplinator.EvaluatorParams{"name": "Larry"},
+					tplinator.EvaluatorParams{"name": "Perry"},
+				),
+			},
+			expected: `<ul><li>Hi, Larry</li><li>Hi, Perry</li></ul>`,
+		},
+		{
+			name:     "inner scope shadows outer scopes",
+			template: `<ul><li go-range="pets"><b go-range="toys">{{go:owner}}:{{go:name}}</b></li></ul>`,
+			params: tplinator.EvaluatorParams{
+				"owner": "nobody",
+				"name":  "root",
+				"pets": tplinator.RangeParams(
+					tplinator.EvaluatorParams{
+						"owner": "Larry",
+						"name":  "pet",
+						"toys":  tplinator.RangeParams(tplinator.EvaluatorParams{"name": "ball"}),
+					},
+				),
+			},
+			expected: `<ul><li><b>Larry:ball</b></li></ul>`,
+		},
+		{
+			name: "else branch inside a range",
+			template: `<ul><li go-range="pets"><b go-if="isDog">{{go:name}} barks</b>` +
+				`<i go-else>{{go:name}} meows</i></li></ul>`,
+			params: tplinator.EvaluatorParams{
+				"pets": tplinator.RangeParams(
+					tplinator.EvaluatorParams{"name": "Larry", "isDog": true},
+					tplinator.EvaluatorParams{"name": "Perry", "isDog": false},
+				),
+			},
+			expected: `<ul><li><b>Larry barks</b></li><li><i>Perry meows</i></li></ul>`,
+		},
+		{
+			name:     "error is not hidden by an outer scope",
+			template: `<ul><li go-range="pets"><b go-if="active">{{go:name}}</b></li></ul>`,
+			params: tplinator.EvaluatorParams{
+				"active": true,
+				"pets": tplinator.RangeParams(
+					tplinator.EvaluatorParams{"name": "Larry", "active": "yes"},
+				),
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(strings.NewReader(tc.template))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			actual, err := tpl.RenderString(tc.params)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expecting an error, got `%v`", actual)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
+
+// mergedScopeEvaluator is a custom evaluator, which gets the scope chain
+// merged into a single EvaluatorParams.
+type mergedScopeEvaluator struct {
+	tplinator.Evaluator
+	scopes []tplinator.EvaluatorParams
+}
+
+func (e *mergedScopeEvaluator) Evaluate(input string, params tplinator.EvaluatorParams) (interface{}, error) {
+	e.scopes = append(e.scopes, params)
+	return e.Evaluator.Evaluate(input, params)
+}
+
+func TestNodeExtension_ScopeChainCustomEvaluator(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<ul><li go-range="pets">{{go:greeting + name}}</li></ul>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	evaluator := &mergedScopeEvaluator{Evaluator: tplinator.NewNativeEvaluator()}
+	tpl.AddExtensionDependencies(evaluatorExtDep{evaluator: evaluator})
+
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{
+		"greeting": "Hi ",
+		"pets":     tplinator.RangeParams(tplinator.EvaluatorParams{"name": "Larry"}),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<ul><li>Hi Larry</li></ul>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+	if scope := evaluator.scopes[len(evaluator.scopes)-1]; scope["greeting"] != "Hi " || scope["name"] != "Larry" {
+		t.Errorf("expecting the merged scope, got %v", scope)
+	}
+}
+
+func TestNodeExtension_StringInterpolationKeepsTemplate(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<a href="/{{go:id}}">{{go:id}}</a>`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	for _, id := range []string{"1", "2"} {
+		actual, err := tpl.RenderString(tplinator.EvaluatorParams{"id": id})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if expected := `<a href="/` + id + `">` + id + `</a>`; actual != expected {
+			t.Errorf("wanted `%v`, got `%v`", expected, actual)
+		}
+	}
+}
+
+type rangedPet struct {
+	Name string
+	Age  int
+}
+
+func TestNodeExtension_RangeSources(t *testing.T) {
+	pets := make(chan rangedPet, 2)
+	pets <- rangedPet{Name: "Larry"}
+	pets <- rangedPet{Name: "Perry"}
+	close(pets)
+
+	testCases := []struct {
+		name     string
+		template string
+		params   tplinator.EvaluatorParams
+
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "slice of strings",
+			template: `<ul><li go-range="tag in tags">{{go:prefix + tag}}</li></ul>`,
+			params:   tplinator.EvaluatorParams{"prefix": "#", "tags": []string{"go", "html"}},
+			expected: `<ul><li>#go</li><li>#html</li></ul>`,
+		},
+		{
+			name:     "slice of structs without an alias",
+			template: `<ul><li go-range="pets">{{go:Name}}</li></ul>`,
+			params:   tplinator.EvaluatorParams{"pets": []rangedPet{{Name: "Larry"}, {Name: "Perry"}}},
+			expected: `<ul><li>Larry</li><li>Perry</li></ul>`,
+		},
+		{
+			name:     "array of pointers",
+			template: `<ul><li go-range="pet in pets">{{go:pet.Name}}</li></ul>`,
+			params:   tplinator.EvaluatorParams{"pets": [2]*rangedPet{{Name: "Larry"}, {Name: "Perry"}}},
+			expected: `<ul><li>Larry</li><li>Perry</li></ul>`,
+		},
+		{
+			name:     "maps from JSON",
+			template: `<ul><li go-range="pets">{{go:name}}</li></ul>`,
+			params: tplinator.EvaluatorParams{"pets": []interface{}{
+				map[string]interface{}{"name": "Larry"},
+				map[string]interface{}{"name": "Perry"},
+			}},
+			expected: `<ul><li>Larry</li><li>Perry</li></ul>`,
+		},
+		{
+			name:     "map with sorted keys",
+			template: `<ul><li go-range="count in counts">{{go:count}}</li></ul>`,
+			params:   tplinator.EvaluatorParams{"counts": map[string]int{"c": 3, "a": 1, "b": 2}},
+			expected: `<ul><li>1</li><li>2</li><li>3</li></ul>`,
+		},
+		{
+			name:     "channel",
+			template: `<ul><li go-range="pet in pets">{{go:pet.Name}}</li></ul>`,
+			params:   tplinator.EvaluatorParams{"pets": pets},
+			expected: `<ul><li>Larry</li><li>Perry</li></ul>`,
+		},
+		{
+			name:     "iterator func",
+			template: `<ul><li go-range="n in evens">{{go:n}}</li></ul>`,
+			params: tplinator.EvaluatorParams{"evens": func(yield func(int) bool) {
+				for n := 0; n < 6 && yield(n); n += 2 {
+				}
+			}},
+			expected: `<ul><li>0</li><li>2</li><li>4</li></ul>`,
+		},
+		{
+			name:     "key-value iterator func",
+			template: `<ul><li go-range="name in names">{{go:name}}</li></ul>`,
+			params: tplinator.EvaluatorParams{"names": func(yield func(int, string) bool) {
+				_ = yield(1, "Larry") && yield(2, "Perry")
+			}},
+			expected: `<ul><li>Larry</li><li>Perry</li></ul>`,
+		},
+		{
+			name:     "nested aliases",
+			template: `<ul><li go-range="pet in pets"><b go-range="toy in pet.toys">{{go:pet.name}}:{{go:toy}}</b></li></ul>`,
+			params: tplinator.EvaluatorParams{"pets": []interface{}{
+				map[string]interface{}{"name": "Larry", "toys": []string{"ball", "bone"}},
+			}},
+			expected: `<ul><li><b>Larry:ball</b><b>Larry:bone</b></li></ul>`,
+		},
+		{
+			name:     "item and index",
+			template: `<ul><li go-range="entry, i in menuEntries">{{go:i}}.{{go:entry}}</li></ul>`,
+			params:   tplinator.EvaluatorParams{"menuEntries": []string{"Home", "About"}},
+			expected: `<ul><li>0.Home</li><li>1.About</li></ul>`,
+		},
+		{
+			name:     "key and value",
+			template: `<dl><dt go-range="key, value in settings">{{go:key}}={{go:value}}</dt></dl>`,
+			params:   tplinator.EvaluatorParams{"settings": map[string]interface{}{"theme": "dark", "lang": "en"}},
+			expected: `<dl><dt>lang=en</dt><dt>theme=dark</dt></dl>`,
+		},
+		{
+			name: "loop metadata",
+			template: `<ul><li go-range="tag in tags" class="{{go:$loop.even ? 'even' : 'odd'}}">` +
+				`{{go:$loop.number}}/{{go:$loop.length}}{{go:$loop.first ? ' first' : ''}}{{go:$loop.last ? ' last' : ''}}</li></ul>`,
+			params: tplinator.EvaluatorParams{"tags": []string{"go", "html", "css"}},
+			expected: `<ul><li class="even">1/3 first</li><li class="odd">2/3</li>` +
+				`<li class="even">3/3 last</li></ul>`,
+		},
+		{
+			name:     "parent loop",
+			template: `<ul><li go-range="row in rows"><b go-range="cell in row">{{go:$loop.parent.index}}{{go:$loop.index}}</b></li></ul>`,
+			params:   tplinator.EvaluatorParams{"rows": [][]int{{1, 2}, {3}}},
+			expected: `<ul><li><b>00</b><b>01</b></li><li><b>10</b></li></ul>`,
+		},
+		{
+			name:     "loop metadata without an alias",
+			template: `<ul><li go-range="pets">{{go:$loop.index}}:{{go:Name}}</li></ul>`,
+			params:   tplinator.EvaluatorParams{"pets": []rangedPet{{Name: "Larry"}, {Name: "Perry"}}},
+			expected: `<ul><li>0:Larry</li><li>1:Perry</li></ul>`,
+		},
+		{
+			name:     "empty fallback",
+			template: `<ul><li go-range="tag in tags">{{go:tag}}</li><li go-range-empty class="{{go:cls}}">No tags</li><li>end</li></ul>`,
+			params:   tplinator.EvaluatorParams{"tags": []string{}, "cls": "empty"},
+			expected: `<ul><li class="empty">No tags</li><li>end</li></ul>`,
+		},
+		{
+			name:     "empty fallback not rendered",
+			template: `<ul><li go-range="tag in tags">{{go:tag}}</li><li go-else>No tags</li><li>end</li></ul>`,
+			params:   tplinator.EvaluatorParams{"tags": []string{"go"}},
+			expected: `<ul><li>go</li><li>end</li></ul>`,
+		},
+		{
+			name: "nested empty fallback",
+			template: `<ul><li go-range="pet in pets"><b go-range="toy in pet.toys">{{go:toy}}</b>` +
+				`<i go-else>{{go:pet.name}} has no toys</i></li></ul>`,
+			params: tplinator.EvaluatorParams{"pets": []interface{}{
+				map[string]interface{}{"name": "Larry", "toys": nil},
+				map[string]interface{}{"name": "Perry", "toys": []string{"ball"}},
+			}},
+			expected: `<ul><li><i>Larry has no toys</i></li><li><b>ball</b></li></ul>`,
+		},
+		{
+			name:     "inclusive numeric range",
+			template: `<p><i go-range="i in 1..5" go-if-class-on="i <= rating">{{go:i}}</i></p>`,
+			params:   tplinator.EvaluatorParams{"rating": 3},
+			expected: `<p><i class="on">1</i><i class="on">2</i><i class="on">3</i><i>4</i><i>5</i></p>`,
+		},
+		{
+			name:     "range func with a step",
+			template: `<p><a go-range="offset in range(0, total, step)" href="?offset={{go:offset}}">{{go:offset / step + 1}}</a></p>`,
+			params:   tplinator.EvaluatorParams{"total": 25, "step": 10},
+			expected: `<p><a href="?offset=0">1</a><a href="?offset=10">2</a><a href="?offset=20">3</a></p>`,
+		},
+		{
+			name:     "descending numeric range",
+			template: `<p><i go-range="i in range(5, 0, -2)">{{go:i}}</i></p>`,
+			expected: `<p><i>5</i><i>3</i><i>1</i></p>`,
+		},
+		{
+			name:     "empty numeric range",
+			template: `<p><i go-range="i in 1..count">{{go:i}}</i></p>`,
+			params:   tplinator.EvaluatorParams{"count": 0},
+			expected: `<p></p>`,
+		},
+		{
+			name:     "repeat",
+			template: `<ul><li go-repeat="3" class="skeleton">{{go:$loop.index}}</li></ul>`,
+			expected: `<ul><li class="skeleton">0</li><li class="skeleton">1</li><li class="skeleton">2</li></ul>`,
+		},
+		{
+			name:     "repeat inside a range",
+			template: `<p><b go-range="pet in pets"><i go-repeat="pet.stars">{{go:pet.name}}</i></b></p>`,
+			params: tplinator.EvaluatorParams{"pets": []interface{}{
+				map[string]interface{}{"name": "Larry", "stars": 2},
+			}},
+			expected: `<p><b><i>Larry</i><i>Larry</i></b></p>`,
+		},
+		{
+			name:          "repeat count that is not an integer",
+			template:      `<ul><li go-repeat="'3'">x</li></ul>`,
+			expectedError: "expecting a count, got string",
+		},
+		{
+			name:          "negative repeat count",
+			template:      `<ul><li go-repeat="n">x</li></ul>`,
+			params:        tplinator.EvaluatorParams{"n": -2},
+			expectedError: "expecting a count that is not negative, got -2",
+		},
+		{
+			name:     "repeat empty fallback",
+			template: `<ul><li go-repeat="n">x</li><li go-else>none</li></ul>`,
+			params:   tplinator.EvaluatorParams{"n": 0},
+			expected: `<ul><li>none</li></ul>`,
+		},
+		{
+			name:     "repeat empty fallback not rendered",
+			template: `<ul><li go-repeat="n">x</li><li go-range-empty>none</li></ul>`,
+			params:   tplinator.EvaluatorParams{"n": 2},
+			expected: `<ul><li>x</li><li>x</li></ul>`,
+		},
+		{
+			name:     "chunk",
+			template: `<div go-range="row in cards | chunk(2)"><i go-range="card in row">{{go:card}}</i></div>`,
+			params:   tplinator.EvaluatorParams{"cards": []string{"a", "b", "c"}},
+			expected: `<div><i>a</i><i>b</i></div><div><i>c</i></div>`,
+		},
+		{
+			name: "group by",
+			template: `<section go-range="category, group in products | groupBy('category')"><h2>{{go:category}}</h2>` +
+				`<p go-range="product in group.items">{{go:product.name}}</p></section>`,
+			params: tplinator.EvaluatorParams{"products": []interface{}{
+				map[string]interface{}{"name": "Apple", "category": "fruit"},
+				map[string]interface{}{"name": "Kale", "category": "vegetable"},
+				map[string]interface{}{"name": "Pear", "category": "fruit"},
+			}},
+			expected: `<section><h2>fruit</h2><p>Apple</p><p>Pear</p></section>` +
+				`<section><h2>vegetable</h2><p>Kale</p></section>`,
+		},
+		{
+			name:     "group by without an alias",
+			template: `<p go-range="pets | sortBy('Name') | groupBy('Age')">{{go:key}}:<b go-range="pet in items">{{go:pet.Name}}</b></p>`,
+			params:   tplinator.EvaluatorParams{"pets": []rangedPet{{Name: "Perry", Age: 2}, {Name: "Larry", Age: 2}, {Name: "Moe", Age: 1}}},
+			expected: `<p>2:<b>Larry</b><b>Perry</b></p><p>1:<b>Moe</b></p>`,
+		},
+		{
+			name:     "where, reverse, offset and limit",
+			template: `<p><i go-range="n in numbers | where(n % 2 == 1) | reverse | offset(1) | limit(max)">{{go:n}}</i></p>`,
+			params:   tplinator.EvaluatorParams{"numbers": []int{1, 2, 3, 4, 5, 6, 7}, "max": 2},
+			expected: `<p><i>5</i><i>3</i></p>`,
+		},
+		{
+			name:     "limit as a filter before where",
+			template: `<p><i go-range="n in numbers | limit(3) | where(n % 2 == 1)">{{go:n}}</i></p>`,
+			params:   tplinator.EvaluatorParams{"numbers": []int{1, 2, 3, 4, 5, 6, 7}},
+			expected: `<p><i>1</i><i>3</i></p>`,
+		},
+		{
+			name:     "limit as a modifier after where",
+			template: `<p><i go-range="n in numbers | where(n % 2 == 1) | limit(3)">{{go:n}}</i></p>`,
+			params:   tplinator.EvaluatorParams{"numbers": []int{1, 2, 3, 4, 5, 6, 7}},
+			expected: `<p><i>1</i><i>3</i><i>5</i></p>`,
+		},
+		{
+			name:          "filter after a modifier",
+			template:      `<p><i go-range="tag in tags | reverse | upper">{{go:tag}}</i></p>`,
+			params:        tplinator.EvaluatorParams{"tags": []string{"go"}},
+			expectedError: "unknown range modifier `upper`",
+		},
+		{
+			name:     "where with merged items",
+			template: `<ul><li go-range="pets | where(Name != skip)">{{go:Name}} {{go:$loop.number}}/{{go:$loop.length}}</li><li go-else>none</li></ul>`,
+			params:   tplinator.EvaluatorParams{"pets": []rangedPet{{Name: "Larry"}, {Name: "Perry"}}, "skip": "Larry"},
+			expected: `<ul><li>Perry 1/1</li></ul>`,
+		},
+		{
+			name:     "modifiers that leave no items",
+			template: `<ul><li go-range="tag in tags | where(tag == 'css')">{{go:tag}}</li><li go-else>none</li></ul>`,
+			params:   tplinator.EvaluatorParams{"tags": []string{"go", "html"}},
+			expected: `<ul><li>none</li></ul>`,
+		},
+		{
+			name:          "invalid modifier argument",
+			template:      `<ul><li go-range="tag in tags | chunk(0)">{{go:tag}}</li></ul>`,
+			params:        tplinator.EvaluatorParams{"tags": []string{"go"}},
+			expectedError: "modifier `chunk`: chunk size must be positive",
+		},
+		{
+			name:          "scalars without an alias",
+			template:      `<ul><li go-range="tags">{{go:tag}}</li></ul>`,
+			params:        tplinator.EvaluatorParams{"tags": []string{"go"}},
+			expectedError: "item 0: cannot use string as params, use `item in tags` to name the items instead",
+		},
+		{
+			name:          "not a range source",
+			template:      `<ul><li go-range="tag in tags">{{go:tag}}</li></ul>`,
+			params:        tplinator.EvaluatorParams{"tags": 3},
+			expectedError: "cannot range over int",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(strings.NewReader(tc.template), tplinator.EvaluatorParserOption(tplinator.NewNativeEvaluator()))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			actual, err := tpl.RenderString(tc.params)
+			if tc.expectedError != "" {
+				if err == nil {
+					t.Errorf("expecting an error, got `%v`", actual)
+				} else if !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("wanted an error containing `%v`, got `%v`", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNodeExtension_RangeNativeSyntaxWithDefaultEvaluator(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<p><i go-range="i in 1..rating">{{go:i}}</i><b go-repeat="n">x</b><b go-else>none</b></p>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{"rating": 3, "n": 0})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `<p><i>1</i><i>2</i><i>3</i><b>none</b></p>`
+	if actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+}
+
+func TestNodeExtension_RangeWithRepeat(t *testing.T) {
+	_, err := tplinator.Tplinate(strings.NewReader(`<p><i go-range="items" go-repeat="2">x</i></p>`))
+	expected := "parser: line 1, column 4: `go-range` cannot be used with `go-repeat`"
+	if err == nil || err.Error() != expected {
+		t.Errorf("wanted the error `%v`, got `%v`", expected, err)
+	}
+}
+
+func TestNodeExtension_RangeModifiersKeepSource(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<p><i go-range="pet in pets | reverse | sortBy('Age', 'desc') | chunk(2)">{{go:pet[0].Name}}</i></p>`,
+	), tplinator.EvaluatorParserOption(tplinator.NewNativeEvaluator()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pets := []rangedPet{{Name: "Larry", Age: 1}, {Name: "Perry", Age: 3}, {Name: "Moe", Age: 2}}
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{"pets": pets})
+	if expected := `<p><i>Perry</i><i>Larry</i></p>`; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+	if pets[0].Name != "Larry" || pets[1].Name != "Perry" || pets[2].Name != "Moe" {
+		t.Errorf("the source was changed: %v", pets)
+	}
+}
+
+func TestNodeExtension_Switch(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<ul><li go-range="order in orders" go-switch="status(order)">` +
+			`<b go-case="'paid'">Paid</b>` +
+			`<b go-case="'pending', 'new'" title="{{go:order.id}}">Waiting</b>` +
+			`<b go-default>Unknown</b>` +
+			`<i>#{{go:order.id}}</i></li></ul>`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	subjectEvaluations := 0
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{
+		"orders": []interface{}{
+			map[string]interface{}{"id": 1, "status": "paid"},
+			map[string]interface{}{"id": 2, "status": "new"},
+			map[string]interface{}{"id": 3, "status": "lost"},
+		},
+		"status": func(order map[string]interface{}) interface{} {
+			subjectEvaluations++
+			return order["status"]
+		},
+	})
+	expected := `<ul><li><b>Paid</b><i>#1</i></li><li><b title="2">Waiting</b><i>#2</i></li>` +
+		`<li><b>Unknown</b><i>#3</i></li></ul>`
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+	if subjectEvaluations != 3 {
+		t.Errorf("wanted the subject to be evaluated once per order, got %d evaluations", subjectEvaluations)
+	}
+
+	tpl, err = tplinator.Tplinate(strings.NewReader(
+		`<div go-switch="count"><p go-case="1">one</p><p go-case="2">two</p></div>`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual, err := tpl.RenderString(tplinator.EvaluatorParams{"count": 3}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<div></div>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+	if actual, err := tpl.RenderString(tplinator.EvaluatorParams{"count": 2.0}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<div><p>two</p></div>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+}
+
+func TestNodeExtension_SwitchErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+
+		expectedError string
+	}{
+		{
+			name:          "interleaved cases",
+			template:      `<div go-switch="s"><b go-case="'a'">A</b><i>mid</i><b go-case="'b'">B</b><b go-default>D</b></div>`,
+			expectedError: "parser: line 1, column 20: the `go-case` and `go-default` children of a `go-switch` must be next to each other, with the `go-default` last",
+		},
+		{
+			name:          "case after the default",
+			template:      `<div go-switch="s"><b go-default>D</b><b go-case="'a'">A</b></div>`,
+			expectedError: "parser: line 1, column 20: the `go-case` and `go-default` children of a `go-switch` must be next to each other, with the `go-default` last",
+		},
+		{
+			name:          "case without a switch",
+			template:      `<div><b go-case="'a'">A</b></div>`,
+			expectedError: "parser: line 1, column 6: `go-case` and `go-default` must be children of a `go-switch`",
+		},
+		{
+			name:          "default without a switch",
+			template:      `<p go-default>D</p>`,
+			expectedError: "parser: line 1, column 1: `go-case` and `go-default` must be children of a `go-switch`",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := tplinator.Tplinate(strings.NewReader(testCase.template))
+			if err == nil || err.Error() != testCase.expectedError {
+				t.Errorf("wanted the error `%v`, got `%v`", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNodeExtension_AttributeBinding(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		params   tplinator.EvaluatorParams
+		expected string
+	}{
+		{
+			name:     "string values are escaped",
+			template: `<a :href="url" go-attr-title="title">link</a>`,
+			params: tplinator.EvaluatorParams{
+				"url": "/search?q=a&b", "title": `"quoted" <title>`,
+			},
+			expected: `<a href="/search?q=a&amp;b" title="&#34;quoted&#34; &lt;title&gt;">link</a>`,
+		},
+		{
+			name:     "true renders a key-only attribute",
+			template: `<a go-attr-aria-current="isCurrent">link</a>`,
+			params:   tplinator.EvaluatorParams{"isCurrent": true},
+			expected: `<a aria-current>link</a>`,
+		},
+		{
+			name:     "nil and false remove the attribute",
+			template: `<a href="#" :href="url" :data-id="id" :title="title">link</a>`,
+			params:   tplinator.EvaluatorParams{"url": nil, "id": false, "title": 3},
+			expected: `<a title="3">link</a>`,
+		},
+		{
+			name:     "filters",
+			template: `<img :alt="caption | default('No caption')"/>`,
+			params:   tplinator.EvaluatorParams{"caption": ""},
+			expected: `<img alt="No caption"/>`,
+		},
+		{
+			name:     "range items",
+			template: `<ul><li go-range="item in items" :data-id="item.id">{{go:item.name}}</li></ul>`,
+			params: tplinator.EvaluatorParams{"items": []interface{}{
+				map[string]interface{}{"id": 1, "name": "a"},
+				map[string]interface{}{"id": nil, "name": "b"},
+			}},
+			expected: `<ul><li data-id="1">a</li><li>b</li></ul>`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			tpl, err := tplinator.Tplinate(strings.NewReader(testCase.template))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual, err := tpl.RenderString(testCase.params)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != testCase.expected {
+				t.Errorf("wanted `%v`, got `%v`", testCase.expected, actual)
+			}
+		})
+	}
+
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<a :href="url">link</a>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tpl.RenderString(tplinator.EvaluatorParams{}); err == nil {
+		t.Errorf("expecting an error")
+	}
+}
+
+func TestNodeExtension_BooleanAttributes(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<form><input type="checkbox" go-checked="accepted" go-disabled="locked" go-required="!accepted"/>` +
+			`<select><option go-range="option in options" go-selected="option == selected">{{go:option}}</option></select>` +
+			`<details go-open="expanded" go-hidden="hidden">details</details></form>`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{
+		"accepted": true, "locked": false, "options": []string{"a", "b"}, "selected": "b",
+		"expanded": false, "hidden": true,
+	})
+	expected := `<form><input type="checkbox" checked/>` +
+		`<select><option>a</option><option selected>b</option></select>` +
+		`<details hidden>details</details></form>`
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+
+	tpl, err = tplinator.Tplinate(strings.NewReader(`<input disabled go-disabled="locked"/>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual, err := tpl.RenderString(tplinator.EvaluatorParams{"locked": false}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<input/>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+	if _, err := tpl.RenderString(tplinator.EvaluatorParams{"locked": "yes"}); err == nil {
+		t.Errorf("expecting an error")
+	}
+}
+
+func TestNodeExtension_StyleBinding(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		params   tplinator.EvaluatorParams
+		expected string
+	}{
+		{
+			name:     "properties",
+			template: `<div go-if-style-display="display" go-if-style-background-color="color">bar</div>`,
+			params:   tplinator.EvaluatorParams{"display": "none", "color": "#fff"},
+			expected: `<div style="display: none; background-color: #fff">bar</div>`,
+		},
+		{
+			name:     "map",
+			template: `<div go-style="{width: pct + '%', color: color, height: nil}">bar</div>`,
+			params:   tplinator.EvaluatorParams{"pct": 42, "color": "red"},
+			expected: `<div style="color: red; width: 42%">bar</div>`,
+		},
+		{
+			name:     "merged with the static style",
+			template: `<div style="color:blue; margin: 0;width: 10px" go-style="styles" go-if-style-color="color">bar</div>`,
+			params: tplinator.EvaluatorParams{
+				"color": "red", "styles": map[string]interface{}{"width": false, "padding": "1em"},
+			},
+			expected: `<div style="color: red; margin: 0; width: 10px; padding: 1em">bar</div>`,
+		},
+		{
+			name:     "nil values keep the static value",
+			template: `<div style="display: block" go-if-style-display="display" go-if-style-color="color" go-style="styles">bar</div>`,
+			params:   tplinator.EvaluatorParams{"display": nil, "color": false, "styles": nil},
+			expected: `<div style="display: block">bar</div>`,
+		},
+		{
+			name:     "nil values in a map restore the static value",
+			template: `<div style="display: block" go-if-style-display="'none'" go-style="{display: nil}">bar</div>`,
+			expected: `<div style="display: block">bar</div>`,
+		},
+		{
+			name:     "values are escaped",
+			template: `<div go-if-style-color="color">bar</div>`,
+			params:   tplinator.EvaluatorParams{"color": `red; background: url("x")`},
+			expected: `<div style="color: red\3b  background: url(\22 x\22 )">bar</div>`,
+		},
+	}
+
+	evaluators := map[string]tplinator.Evaluator{
+		"govaluate": tplinator.NewGovaluateEvaluator(),
+		"native":    tplinator.NewNativeEvaluator(),
+	}
+	for evaluatorName, evaluator := range evaluators {
+		for _, testCase := range testCases {
+			t.Run(evaluatorName+"/"+testCase.name, func(t *testing.T) {
+				tpl, err := tplinator.Tplinate(strings.NewReader(testCase.template), tplinator.EvaluatorParserOption(evaluator))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				actual, err := tpl.RenderString(testCase.params)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				} else if actual != testCase.expected {
+					t.Errorf("wanted `%v`, got `%v`", testCase.expected, actual)
+				}
+			})
+		}
+	}
+
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<div go-style="styles">bar</div>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, styles := range []interface{}{"color: red", map[string]interface{}{"color: red; x": "blue"}} {
+		if _, err := tpl.RenderString(tplinator.EvaluatorParams{"styles": styles}); err == nil {
+			t.Errorf("expecting an error for %v", styles)
+		}
+	}
+}
+
+func TestNodeExtension_ClassBinding(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		params   tplinator.EvaluatorParams
+		expected string
+	}{
+		{
+			name:     "string",
+			template: `<span class="badge" go-class="'badge-' + status">{{go:status}}</span>`,
+			params:   tplinator.EvaluatorParams{"status": "paid"},
+			expected: `<span class="badge badge-paid">paid</span>`,
+		},
+		{
+			name:     "map",
+			template: `<div go-class="{'md:w-1/2': isHalf, active: isActive, 'p-2 m-1': true}">x</div>`,
+			params:   tplinator.EvaluatorParams{"isHalf": true, "isActive": false},
+			expected: `<div class="md:w-1/2 p-2 m-1">x</div>`,
+		},
+		{
+			name:     "list",
+			template: `<div go-class="['card', size, nil, {selected: isSelected}]">x</div>`,
+			params:   tplinator.EvaluatorParams{"size": "card-lg", "isSelected": true},
+			expected: `<div class="card card-lg selected">x</div>`,
+		},
+		{
+			name:     "list of literals",
+			template: `<div class="a" go-class="['b', {d: true}]">x</div>`,
+			expected: `<div class="a b d">x</div>`,
+		},
+		{
+			name:     "merged and de-duplicated",
+			template: `<div class="card card" go-if-class-active="isActive" go-class="classes">x</div>`,
+			params:   tplinator.EvaluatorParams{"isActive": true, "classes": []string{"active", "card", "wide"}},
+			expected: `<div class="card active wide">x</div>`,
+		},
+		{
+			name:     "no classes",
+			template: `<div go-class="classes">x</div>`,
+			params:   tplinator.EvaluatorParams{"classes": nil},
+			expected: `<div>x</div>`,
+		},
+	}
+
+	evaluators := map[string]tplinator.Evaluator{
+		"govaluate": tplinator.NewGovaluateEvaluator(),
+		"native":    tplinator.NewNativeEvaluator(),
+	}
+	for evaluatorName, evaluator := range evaluators {
+		for _, testCase := range testCases {
+			t.Run(evaluatorName+"/"+testCase.name, func(t *testing.T) {
+				tpl, err := tplinator.Tplinate(strings.NewReader(testCase.template), tplinator.EvaluatorParserOption(evaluator))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				actual, err := tpl.RenderString(testCase.params)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				} else if actual != testCase.expected {
+					t.Errorf("wanted `%v`, got `%v`", testCase.expected, actual)
+				}
+			})
+		}
+	}
+
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<div go-class="classes">x</div>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, classes := range []interface{}{42, map[string]interface{}{"active": "yes"}} {
+		if _, err := tpl.RenderString(tplinator.EvaluatorParams{"classes": classes}); err == nil {
+			t.Errorf("expecting an error for %v", classes)
+		}
+	}
+}
+
+func TestNodeExtension_Text(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<ul><li go-range="user in users"><span class="name" go-text="user.name | upper">Jane <b>Doe</b></span>` +
+			`<span go-text="user.age">{{go:placeholder}}</span></li></ul>`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{
+		"users": []interface{}{
+			map[string]interface{}{"name": "<script>", "age": 30},
+			map[string]interface{}{"name": "Tom & Jerry", "age": nil},
+		},
+	})
+	expected := `<ul><li><span class="name">&lt;SCRIPT&gt;</span><span>30</span></li>` +
+		`<li><span class="name">TOM &amp; JERRY</span><span></span></li></ul>`
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+
+	_, err = tplinator.Tplinate(strings.NewReader(`<p go-text="name" go-html="bio">placeholder</p>`))
+	expectedError := "parser: line 1, column 1: `go-text` cannot be used with `go-html`"
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("wanted the error `%v`, got `%v`", expectedError, err)
+	}
+}
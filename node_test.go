@@ -72,6 +72,18 @@ func TestCreateNode(t *testing.T) {
 			expectedStartTag: `<img src="/static/images/cat.png"/>`,
 			expectedEndTag:   "",
 		},
+		{
+			name: "virtual element (go-template) node test",
+
+			data:     "go-template",
+			nodeType: html.ElementNode,
+			attributes: []html.Attribute{
+				{Key: "class", Val: "ignored"},
+			},
+
+			expectedStartTag: "",
+			expectedEndTag:   "",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -759,3 +771,24 @@ func TestNode_RemoveChild(t *testing.T) {
 		})
 	}
 }
+
+func TestNode_Scope(t *testing.T) {
+	divNode := tplinator.CreateNode(html.ElementNode, "div", nil, false)
+	divNode.SetContextParams(tplinator.EvaluatorParams{"name": "div", "isDiv": true})
+	pNode := tplinator.CreateNode(html.ElementNode, "p", nil, false)
+	pNode.SetContextParams(tplinator.EvaluatorParams{"name": "p"})
+	divNode.AppendChild(pNode)
+
+	scope := pNode.Scope(tplinator.EvaluatorParams{"name": "root", "isRoot": true})
+	if scope["name"] != "p" {
+		t.Errorf("wanted `p`, got `%v`", scope["name"])
+	}
+	if scope["isDiv"] != true || scope["isRoot"] != true {
+		t.Errorf("expecting the scope to have the params of the outer scopes. scope: %v", scope)
+	}
+
+	pCopy := tplinator.CopyNode(pNode)
+	if scope := pCopy.Scope(nil); scope["isDiv"] != true {
+		t.Errorf("expecting the copy to keep the scope of the node. scope: %v", scope)
+	}
+}
@@ -2,6 +2,7 @@ package tplinator
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 
@@ -19,18 +20,39 @@ func NodeProcessorsParserOption(npfs ...NodeProcessorFunc) ParserOptionFunc {
 	}
 }
 
+func FuncsParserOption(funcs FuncMap) ParserOptionFunc {
+	return func(p *Parser) {
+		p.funcs = mergeFuncMaps(funcs, p.funcs)
+	}
+}
+
 type Parser struct {
 	tokenizer *html.Tokenizer
 
 	nodeProcessors []NodeProcessorFunc
+	funcs          FuncMap
+	evaluator      Evaluator
+	missingKey     MissingKeyPolicy
+	limits         *Limits
 }
 
-func ParseNodes(rdr io.Reader, opts ...ParserOptionFunc) ([]*Node, error) {
-	parser := Parser{
+func newParser(rdr io.Reader, opts ...ParserOptionFunc) (*Parser, error) {
+	parser := &Parser{
 		tokenizer: html.NewTokenizer(rdr),
 	}
 	for _, parserOption := range opts {
-		parserOption(&parser)
+		parserOption(parser)
+	}
+	if err := validateFuncMap(parser.funcs); err != nil {
+		return nil, fmt.Errorf("parser: %v", err)
+	}
+	return parser, nil
+}
+
+func ParseNodes(rdr io.Reader, opts ...ParserOptionFunc) ([]*Node, error) {
+	parser, err := newParser(rdr, opts...)
+	if err != nil {
+		return nil, err
 	}
 	return parser.parse()
 }
@@ -47,9 +69,16 @@ func (p Parser) parse() ([]*Node, error) {
 		}
 	}
 
+	position := Position{Line: 1, Column: 1}
+
 	for {
 		tokenType := p.tokenizer.Next()
 
+		// keep track of where the token starts before Tokenizer#Token
+		// changes its raw text
+		tokenPosition := position
+		position = position.advance(string(p.tokenizer.Raw()))
+
 		err := p.tokenizer.Err()
 		if err != nil {
 			if err != io.EOF {
@@ -59,7 +88,9 @@ func (p Parser) parse() ([]*Node, error) {
 					"parser: reached the end of the file unexpectedly",
 				)
 			}
-			p.processNodes(templateNodes)
+			if err := p.processNodes(templateNodes); err != nil {
+				return templateNodes, err
+			}
 			return templateNodes, nil
 		}
 
@@ -73,11 +104,13 @@ func (p Parser) parse() ([]*Node, error) {
 			token := p.tokenizer.Token()
 			trimmedText := strings.TrimSpace(token.Data)
 			if len(trimmedText) > 0 {
-				postProcessThenAddNode(
-					CreateNode(
-						html.TextNode, trimmedText, nil, false,
-					),
+				textNode := CreateNode(
+					html.TextNode, trimmedText, nil, false,
+				)
+				textNode.position = tokenPosition.advance(
+					token.Data[:strings.Index(token.Data, trimmedText)],
 				)
+				postProcessThenAddNode(textNode)
 			}
 		case html.DoctypeToken:
 			// the doctype token should be the first token to be found
@@ -88,23 +121,24 @@ func (p Parser) parse() ([]*Node, error) {
 				)
 			}
 			token := p.tokenizer.Token()
-			templateNodes = append(templateNodes,
-				CreateNode(
-					html.DoctypeNode, token.Data, nil, false,
-				),
+			doctypeNode := CreateNode(
+				html.DoctypeNode, token.Data, nil, false,
 			)
+			doctypeNode.position = tokenPosition
+			templateNodes = append(templateNodes, doctypeNode)
 		case html.SelfClosingTagToken:
 			token := p.tokenizer.Token()
-			postProcessThenAddNode(
-				CreateNode(
-					html.ElementNode, token.Data, token.Attr, true,
-				),
+			newNode := CreateNode(
+				html.ElementNode, token.Data, token.Attr, true,
 			)
+			newNode.position = tokenPosition
+			postProcessThenAddNode(newNode)
 		case html.StartTagToken:
 			token := p.tokenizer.Token()
 			newNode := CreateNode(
 				html.ElementNode, token.Data, token.Attr, false,
 			)
+			newNode.position = tokenPosition
 			postProcessThenAddNode(newNode)
 			parserStack.Push(newNode)
 		case html.EndTagToken:
@@ -135,7 +169,7 @@ func (p Parser) parse() ([]*Node, error) {
 	}
 }
 
-func (p Parser) processNodes(rootNodes []*Node) {
+func (p Parser) processNodes(rootNodes []*Node) error {
 	nodeStack := stackgo.NewStack()
 	for _, rootNode := range rootNodes {
 		nodeStack.Push(rootNode)
@@ -145,10 +179,16 @@ func (p Parser) processNodes(rootNodes []*Node) {
 			for _, processNode := range p.nodeProcessors {
 				processNode(node)
 			}
+			for _, extension := range node.extensions {
+				if invalidDirective, isInvalid := extension.(*invalidDirectiveExtension); isInvalid {
+					return fmt.Errorf("parser: %v", invalidDirective.err)
+				}
+			}
 			node.Children(func(_ int, child *Node) bool {
 				nodeStack.Push(child)
 				return true
 			})
 		}
 	}
+	return nil
 }
@@ -147,3 +147,36 @@ func TestParseTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTemplate_Positions(t *testing.T) {
+	rootNodes, err := tplinator.ParseNodes(strings.NewReader(
+		"<div>\n  <p class=\"a\">\n    Hello, ñ\n  </p><br/>\n</div>",
+	))
+	if err != nil {
+		t.Errorf("failed to parse input. cause: %v", err)
+		return
+	}
+
+	divNode := rootNodes[0]
+	pNode := divNode.FirstChild()
+	textNode := pNode.FirstChild()
+	brNode := pNode.NextSibling()
+
+	expectedPositions := []struct {
+		node     *tplinator.Node
+		expected tplinator.Position
+	}{
+		{divNode, tplinator.Position{Line: 1, Column: 1}},
+		{pNode, tplinator.Position{Line: 2, Column: 3}},
+		{textNode, tplinator.Position{Line: 3, Column: 5}},
+		{brNode, tplinator.Position{Line: 4, Column: 7}},
+	}
+	for _, ep := range expectedPositions {
+		if actual := ep.node.Position(); actual != ep.expected {
+			t.Errorf("wanted the position of `%v` to be %v, got %v", ep.node.Data, ep.expected, actual)
+		}
+	}
+	if copyPosition := tplinator.CopyNode(pNode).Position(); copyPosition != pNode.Position() {
+		t.Errorf("expecting the copy to have the same position, got %v", copyPosition)
+	}
+}
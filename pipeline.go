@@ -0,0 +1,260 @@
+package tplinator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type pipelineFilterCall struct {
+	name string
+	args []string
+}
+
+type pipeline struct {
+	expression string
+	filters    []pipelineFilterCall
+}
+
+func parsePipeline(input string) (pipeline, error) {
+	segments, err := splitTopLevel(input, '|')
+	if err != nil {
+		return pipeline{}, err
+	}
+
+	p := pipeline{expression: strings.TrimSpace(segments[0])}
+	if p.expression == "" {
+		return pipeline{}, fmt.Errorf("pipeline: `%v` does not have an expression", input)
+	}
+	for _, segment := range segments[1:] {
+		filterCall, err := parsePipelineFilterCall(strings.TrimSpace(segment))
+		if err != nil {
+			return pipeline{}, fmt.Errorf("pipeline: %v", err)
+		}
+		p.filters = append(p.filters, filterCall)
+	}
+	return p, nil
+}
+
+func parsePipelineFilterCall(segment string) (pipelineFilterCall, error) {
+	openParenIdx := strings.IndexByte(segment, '(')
+	if openParenIdx < 0 {
+		if !isIdentifier(segment) {
+			return pipelineFilterCall{}, fmt.Errorf("`%v` is not a valid filter name", segment)
+		}
+		return pipelineFilterCall{name: segment}, nil
+	}
+
+	name := strings.TrimSpace(segment[:openParenIdx])
+	if !isIdentifier(name) {
+		return pipelineFilterCall{}, fmt.Errorf("`%v` is not a valid filter name", name)
+	}
+	if !strings.HasSuffix(segment, ")") {
+		return pipelineFilterCall{}, fmt.Errorf("filter `%v` has an unclosed argument list", name)
+	}
+
+	argList := strings.TrimSpace(segment[openParenIdx+1 : len(segment)-1])
+	if argList == "" {
+		return pipelineFilterCall{name: name}, nil
+	}
+	args, err := splitTopLevel(argList, ',')
+	if err != nil {
+		return pipelineFilterCall{}, err
+	}
+	for argIdx, arg := range args {
+		args[argIdx] = strings.TrimSpace(arg)
+		if args[argIdx] == "" {
+			return pipelineFilterCall{}, fmt.Errorf("filter `%v` has an empty argument", name)
+		}
+	}
+	return pipelineFilterCall{name: name, args: args}, nil
+}
+
+// splitTopLevel splits the input on the separator but ignores the
+// separators that are inside string literals and brackets. A pipe
+// separator that is doubled (the `||` operator) is never split on.
+func splitTopLevel(input string, separator byte) ([]string, error) {
+	var segments []string
+	var quote byte
+	depth := 0
+	segmentStart := 0
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("`%v` has an unexpected `%c`", input, c)
+			}
+		case c == separator && depth == 0:
+			if separator == '|' && i+1 < len(input) && input[i+1] == '|' {
+				i++
+				continue
+			}
+			segments = append(segments, input[segmentStart:i])
+			segmentStart = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("`%v` has an unclosed string literal", input)
+	} else if depth != 0 {
+		return nil, fmt.Errorf("`%v` has an unclosed bracket", input)
+	}
+
+	return append(segments, input[segmentStart:]), nil
+}
+
+func isIdentifier(str string) bool {
+	if str == "" {
+		return false
+	}
+	for i, r := range str {
+		isLetter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		if !isLetter && (i == 0 || !isDigit) {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluatePipeline evaluates the expression of the pipeline then passes
+// its value through the filters. If a variable is missing and the
+// MissingKeyPolicy allows it, the built-in evaluators use its zero value
+// and a missingValue is returned if the value of the whole expression is
+// missing. The filters are still applied to a nil value when the policy
+// is MissingKeyZero so that filters like `default` can replace it.
+func evaluatePipeline(
+	node *Node, dependencies ExtensionDependencies,
+	input string, params EvaluatorParams,
+) (interface{}, error) {
+	p, err := parsePipeline(input)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := renderLimits(dependencies)
+	if limits.MaxExpressionLength > 0 && len(input) > limits.MaxExpressionLength {
+		return nil, &LimitError{
+			Limit:    ExpressionLengthLimit,
+			Max:      limits.MaxExpressionLength,
+			Position: node.Position(),
+		}
+	}
+
+	scope := newScopeChain(node, params)
+
+	evaluator := dependencies.Get(EvaluatorExtDepKey).(Evaluator)
+	funcs, _ := dependencies.Get(FuncsExtDepKey).(FuncMap)
+
+	// the functions that use the locale are bound to the node's scope.
+	// The registered functions take precedence over them and the other
+	// built-in functions.
+	var translateErr error
+	funcs = mergeFuncMaps(funcs, localeFuncs(node, dependencies, scope, &translateErr), builtinFuncs)
+	if configurableEvaluator, isConfigurable := evaluator.(ConfigurableEvaluator); isConfigurable {
+		evaluator = configurableEvaluator.WithOptions(EvaluatorOptions{
+			Funcs:         funcs,
+			MaxOperations: limits.MaxOperations,
+			MissingKey:    missingKeyPolicy(dependencies),
+		})
+	}
+
+	// the evaluators that cannot look up the variables through the chain
+	// get the merged scope, which is only merged once
+	var mergedScope EvaluatorParams
+	chainEvaluator, isChainEvaluator := evaluator.(scopeEvaluator)
+
+	var missing *missingValue
+	evaluate := func(expression string) (interface{}, error) {
+		var value interface{}
+		var err error
+		if isChainEvaluator {
+			value, err = chainEvaluator.evaluateScope(expression, scope)
+		} else {
+			if mergedScope == nil {
+				mergedScope = scope.merge()
+			}
+			value, err = evaluator.Evaluate(expression, mergedScope)
+		}
+		if err != nil {
+			if translateErr != nil {
+				return nil, translateErr
+			}
+			var limitErr *LimitError
+			if errors.As(err, &limitErr) && !limitErr.Position.IsValid() {
+				return nil, &LimitError{Limit: limitErr.Limit, Max: limitErr.Max, Position: node.Position()}
+			}
+			if missing, err = handleMissingVariable(node, dependencies, input, err); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		}
+		// the built-in evaluators only evaluate to a missingValue if the
+		// value only comes from missing variables, e.g. `user.name`
+		// without a `user`
+		if missingResult, isMissing := value.(missingValue); isMissing {
+			missing = &missingResult
+			return nil, nil
+		}
+		return value, nil
+	}
+
+	value, err := evaluate(p.expression)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, _ := dependencies.Get(FiltersExtDepKey).(FilterMap)
+	for _, filterCall := range p.filters {
+		if missing != nil && missing.policy == MissingKeyPlaceholder {
+			break
+		}
+		filter, hasFilter := filters[filterCall.name]
+		if !hasFilter {
+			return nil, fmt.Errorf("pipeline: unknown filter `%v`", filterCall.name)
+		}
+		args := make([]interface{}, len(filterCall.args))
+		for argIdx, arg := range filterCall.args {
+			if args[argIdx], err = evaluate(arg); err != nil {
+				return nil, err
+			}
+		}
+		if value, err = filter(value, args...); err != nil {
+			return nil, fmt.Errorf("pipeline: filter `%v`: %v", filterCall.name, err)
+		}
+	}
+	if missing != nil && (value == nil || missing.policy == MissingKeyPlaceholder) {
+		return *missing, nil
+	}
+	return value, nil
+}
+
+func evaluateBoolPipeline(
+	node *Node, dependencies ExtensionDependencies,
+	input string, params EvaluatorParams,
+) (bool, error) {
+	result, err := evaluatePipeline(node, dependencies, input, params)
+	if err != nil {
+		return false, err
+	}
+	if _, isMissing := result.(missingValue); isMissing {
+		return false, nil
+	}
+	boolResult, isBool := result.(bool)
+	if !isBool {
+		return false, errors.New("pipeline: `" + input + "` is not a conditional expression")
+	}
+	return boolResult, nil
+}
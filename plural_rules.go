@@ -0,0 +1,187 @@
+package tplinator
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PluralCategory is a CLDR plural category.
+type PluralCategory string
+
+const (
+	PluralZero  PluralCategory = "zero"
+	PluralOne   PluralCategory = "one"
+	PluralTwo   PluralCategory = "two"
+	PluralFew   PluralCategory = "few"
+	PluralMany  PluralCategory = "many"
+	PluralOther PluralCategory = "other"
+)
+
+func isPluralCategory(str string) bool {
+	switch PluralCategory(str) {
+	case PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// pluralOperands are the operands of the CLDR plural rules: n is the
+// absolute value, i its integer digits, v the number of its visible
+// fraction digits and f the visible fraction digits themselves.
+type pluralOperands struct {
+	n float64
+	i int
+	v int
+	f int
+}
+
+func newPluralOperands(count interface{}) (pluralOperands, bool) {
+	number, isNumber := toNumber(count)
+	if !isNumber {
+		return pluralOperands{}, false
+	}
+	if number.isInt {
+		i := number.intValue
+		if i < 0 {
+			i = -i
+		}
+		return pluralOperands{n: float64(i), i: i}, true
+	}
+
+	n := math.Abs(number.floatValue)
+	operands := pluralOperands{n: n, i: int(n)}
+	formatted := strconv.FormatFloat(n, 'f', -1, 64)
+	if dotIdx := strings.IndexByte(formatted, '.'); dotIdx >= 0 {
+		fraction := formatted[dotIdx+1:]
+		operands.v = len(fraction)
+		operands.f, _ = strconv.Atoi(fraction)
+	}
+	return operands, true
+}
+
+type pluralRule func(op pluralOperands) PluralCategory
+
+func inRange(value, min, max int) bool {
+	return value >= min && value <= max
+}
+
+// pluralRules has the CLDR cardinal plural rules of the languages which
+// are supported out of the box. The languages which do not have a rule
+// use pluralRuleOneOther.
+var pluralRules = map[string]pluralRule{
+	"ja": pluralRuleOther, "zh": pluralRuleOther, "ko": pluralRuleOther,
+	"vi": pluralRuleOther, "th": pluralRuleOther, "id": pluralRuleOther,
+	"ms": pluralRuleOther,
+
+	"fr": pluralRuleFrench, "pt": pluralRuleFrench,
+
+	"ru": pluralRuleEastSlavic, "uk": pluralRuleEastSlavic, "be": pluralRuleEastSlavic,
+
+	"pl": pluralRulePolish,
+	"cs": pluralRuleCzech, "sk": pluralRuleCzech,
+	"ar": pluralRuleArabic,
+}
+
+func pluralCategoryOf(locale string, count interface{}) PluralCategory {
+	operands, isNumber := newPluralOperands(count)
+	if !isNumber {
+		return PluralOther
+	}
+	rule, hasRule := pluralRules[localeLanguage(locale)]
+	if !hasRule {
+		rule = pluralRuleOneOther
+	}
+	return rule(operands)
+}
+
+func pluralRuleOther(_ pluralOperands) PluralCategory {
+	return PluralOther
+}
+
+// pluralRuleOneOther is the rule of English, German, Dutch, Italian and
+// many other languages.
+func pluralRuleOneOther(op pluralOperands) PluralCategory {
+	if op.i == 1 && op.v == 0 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralRuleFrench(op pluralOperands) PluralCategory {
+	if op.i == 0 || op.i == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralRuleEastSlavic(op pluralOperands) PluralCategory {
+	if op.v != 0 {
+		return PluralOther
+	}
+	switch mod10, mod100 := op.i%10, op.i%100; {
+	case mod10 == 1 && mod100 != 11:
+		return PluralOne
+	case inRange(mod10, 2, 4) && !inRange(mod100, 12, 14):
+		return PluralFew
+	default:
+		return PluralMany
+	}
+}
+
+func pluralRulePolish(op pluralOperands) PluralCategory {
+	if op.v != 0 {
+		return PluralOther
+	}
+	switch mod10, mod100 := op.i%10, op.i%100; {
+	case op.i == 1:
+		return PluralOne
+	case inRange(mod10, 2, 4) && !inRange(mod100, 12, 14):
+		return PluralFew
+	default:
+		return PluralMany
+	}
+}
+
+func pluralRuleCzech(op pluralOperands) PluralCategory {
+	switch {
+	case op.v != 0:
+		return PluralMany
+	case op.i == 1:
+		return PluralOne
+	case inRange(op.i, 2, 4):
+		return PluralFew
+	default:
+		return PluralOther
+	}
+}
+
+func pluralRuleArabic(op pluralOperands) PluralCategory {
+	if op.n != math.Trunc(op.n) {
+		return PluralOther
+	}
+	switch n, mod100 := int(op.n), int(op.n)%100; {
+	case n == 0:
+		return PluralZero
+	case n == 1:
+		return PluralOne
+	case n == 2:
+		return PluralTwo
+	case inRange(mod100, 3, 10):
+		return PluralFew
+	case inRange(mod100, 11, 99):
+		return PluralMany
+	default:
+		return PluralOther
+	}
+}
+
+// localeLanguage returns the language of a locale, e.g. `pt` for `pt-BR`
+// or `pt_BR.UTF-8`.
+func localeLanguage(locale string) string {
+	if sepIdx := strings.IndexAny(locale, "-_."); sepIdx >= 0 {
+		locale = locale[:sepIdx]
+	}
+	return strings.ToLower(locale)
+}
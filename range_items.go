@@ -0,0 +1,225 @@
+package tplinator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rangeItem is an item of the source of a range. Its key is the index of
+// the item unless the source is keyed, i.e. a map or an iterator func that
+// yields keys too.
+type rangeItem struct {
+	key   interface{}
+	value interface{}
+	keyed bool
+}
+
+// RangeLoop is the `$loop` variable of the copies of an element with a
+// `go-range` attribute. Parent is the loop of the enclosing range, if any.
+type RangeLoop struct {
+	Index  int        `json:"index"`
+	Number int        `json:"number"`
+	First  bool       `json:"first"`
+	Last   bool       `json:"last"`
+	Even   bool       `json:"even"`
+	Odd    bool       `json:"odd"`
+	Length int        `json:"length"`
+	Parent *RangeLoop `json:"parent"`
+}
+
+// LoopParam is the name of the RangeLoop in the scope of a range item.
+const LoopParam = "$loop"
+
+func newRangeLoop(index, length int, parent *RangeLoop) *RangeLoop {
+	return &RangeLoop{
+		Index:  index,
+		Number: index + 1,
+		First:  index == 0,
+		Last:   index == length-1,
+		Even:   index%2 == 0,
+		Odd:    index%2 == 1,
+		Length: length,
+		Parent: parent,
+	}
+}
+
+// intRange returns an iterator func over the ints from start up to but not
+// including stop.
+func intRange(start, stop, step int) func(yield func(int) bool) {
+	return func(yield func(int) bool) {
+		for n := start; (step > 0 && n < stop) || (step < 0 && n > stop); n += step {
+			if !yield(n) {
+				return
+			}
+		}
+	}
+}
+
+var intRangeType = reflect.TypeOf(intRange(0, 0, 1))
+
+var rangeAliasRegex = regexp.MustCompile(
+	`^\s*([A-Za-z_][A-Za-z0-9_]*)(?:\s*,\s*([A-Za-z_][A-Za-z0-9_]*))?\s+in\s+(\S.*)$`,
+)
+
+// parseRangeDeclaration splits a `go-range` value like `item in items` or
+// `key, value in settings` into the aliases of the items and the
+// expression of their source. The aliases are empty if the declaration
+// only has an expression.
+func parseRangeDeclaration(declaration string) (alias string, secondAlias string, source string) {
+	if matches := rangeAliasRegex.FindStringSubmatch(declaration); matches != nil {
+		return matches[1], matches[2], strings.TrimSpace(matches[3])
+	}
+	return "", "", strings.TrimSpace(declaration)
+}
+
+// forEachRangeItem calls itemFunc with each of the items of a slice,
+// array, map, channel or iterator func. The items of a map are sorted by
+// their keys, a channel is received from until it is closed and an
+// iterator func is either a `func(yield func(V) bool)` or a
+// `func(yield func(K, V) bool)`. An error returned by itemFunc stops the
+// iteration.
+func forEachRangeItem(value interface{}, itemFunc func(rangeItem) error) error {
+	switch value := value.(type) {
+	case nil, missingValue:
+		return nil
+	case RangeEvaluatorParams:
+		for itemIdx, itemParams := range value {
+			if err := itemFunc(rangeItem{key: itemIdx, value: itemParams}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rv, isValid := indirectValue(reflect.ValueOf(value))
+	if !isValid {
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for itemIdx := 0; itemIdx < rv.Len(); itemIdx++ {
+			if err := itemFunc(rangeItem{key: itemIdx, value: rv.Index(itemIdx).Interface()}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		for _, key := range sortedMapKeys(rv) {
+			item := rangeItem{key: key.Interface(), value: rv.MapIndex(key).Interface(), keyed: true}
+			if err := itemFunc(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Chan:
+		if rv.Type().ChanDir()&reflect.RecvDir == 0 {
+			return fmt.Errorf("cannot range over the send-only %T", value)
+		}
+		for itemIdx := 0; ; itemIdx++ {
+			item, isOpen := rv.Recv()
+			if !isOpen {
+				return nil
+			}
+			if err := itemFunc(rangeItem{key: itemIdx, value: reflectValueInterface(item)}); err != nil {
+				return err
+			}
+		}
+	case reflect.Func:
+		if !isIteratorFuncType(rv.Type()) {
+			return fmt.Errorf("cannot range over %T", value)
+		}
+		return forEachIteratorItem(rv, itemFunc)
+	default:
+		return fmt.Errorf("cannot range over %T", value)
+	}
+}
+
+func forEachIteratorItem(iterator reflect.Value, itemFunc func(rangeItem) error) error {
+	yieldType := iterator.Type().In(0)
+	itemIdx := 0
+	var itemErr error
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		if itemErr == nil {
+			item := rangeItem{key: itemIdx, value: reflectValueInterface(args[len(args)-1])}
+			if len(args) == 2 {
+				item.key, item.keyed = reflectValueInterface(args[0]), true
+			}
+			itemIdx++
+			itemErr = itemFunc(item)
+		}
+		return []reflect.Value{reflect.ValueOf(itemErr == nil).Convert(yieldType.Out(0))}
+	})
+	iterator.Call([]reflect.Value{yield})
+	return itemErr
+}
+
+func isIteratorFuncType(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return false
+	}
+	yieldType := t.In(0)
+	return yieldType.Kind() == reflect.Func &&
+		(yieldType.NumIn() == 1 || yieldType.NumIn() == 2) &&
+		yieldType.NumOut() == 1 && yieldType.Out(0).Kind() == reflect.Bool
+}
+
+func reflectValueInterface(rv reflect.Value) interface{} {
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// sortedMapKeys sorts the keys of a map so that ranging over it always
+// produces the same output. Keys that are not strings, numbers or
+// booleans are sorted by their formatted value.
+func sortedMapKeys(rv reflect.Value) []reflect.Value {
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case a.Kind() == reflect.String && b.Kind() == reflect.String:
+			return a.String() < b.String()
+		case isNumberKind(a.Kind()) && isNumberKind(b.Kind()):
+			aNumber, _ := toFloat64(a.Interface())
+			bNumber, _ := toFloat64(b.Interface())
+			return aNumber < bNumber
+		case a.Kind() == reflect.Bool && b.Kind() == reflect.Bool:
+			return !a.Bool() && b.Bool()
+		default:
+			return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+		}
+	})
+	return keys
+}
+
+// rangeItemTypes is the static counterpart of forEachRangeItem. It
+// returns the types of the keys and the values of the items of a range
+// source and whether the source is keyed.
+func rangeItemTypes(t reflect.Type) (keyType reflect.Type, valueType reflect.Type, keyed bool, err error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return intType, t.Elem(), false, nil
+	case reflect.Map:
+		return t.Key(), t.Elem(), true, nil
+	case reflect.Chan:
+		return intType, t.Elem(), false, nil
+	case reflect.Func:
+		if isIteratorFuncType(t) {
+			yieldType := t.In(0)
+			if yieldType.NumIn() == 2 {
+				return yieldType.In(0), yieldType.In(1), true, nil
+			}
+			return intType, yieldType.In(0), false, nil
+		}
+	case reflect.Interface:
+		return nil, nil, false, nil
+	}
+	return nil, nil, false, fmt.Errorf("cannot range over %v", t)
+}
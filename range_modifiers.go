@@ -0,0 +1,280 @@
+package tplinator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RangeGroup is an item of a range that uses the `groupBy` modifier. Items
+// are the items of the source that have the same Key, in their original
+// order.
+type RangeGroup struct {
+	Key   interface{} `json:"key"`
+	Items interface{} `json:"items"`
+}
+
+var (
+	rangeGroupType = reflect.TypeOf(&RangeGroup{})
+	interfaceType  = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// rangeModifierNames are the names of the calls that can come at the end
+// of a `go-range` declaration, e.g. `card in cards | where(card.visible) |
+// chunk(3)`. Unlike filters, they are applied by the range extension to
+// the items of the source.
+var rangeModifierNames = map[string]bool{
+	"chunk":   true,
+	"groupBy": true,
+	"sortBy":  true,
+	"reverse": true,
+	"where":   true,
+	"limit":   true,
+	"offset":  true,
+}
+
+// splitRangeModifiers splits the range modifiers off the source of a
+// range. The calls before the first one that is only a range modifier
+// (i.e. not `sortBy` or `limit`, which are also filters) are filters, and
+// it and the calls after it are all range modifiers. The source is
+// returned as is if it cannot be split so that evaluating it reports the
+// error.
+func splitRangeModifiers(source string) (string, []pipelineFilterCall) {
+	segments, err := splitTopLevel(source, '|')
+	if err != nil {
+		return source, nil
+	}
+	defaultFilters := DefaultFilters()
+	for sourceEnd := 1; sourceEnd < len(segments); sourceEnd++ {
+		modifier, err := parsePipelineFilterCall(strings.TrimSpace(segments[sourceEnd]))
+		if err != nil {
+			return source, nil
+		}
+		if _, isFilter := defaultFilters[modifier.name]; isFilter || !rangeModifierNames[modifier.name] {
+			continue
+		}
+
+		modifiers := []pipelineFilterCall{modifier}
+		for _, segment := range segments[sourceEnd+1:] {
+			modifier, err := parsePipelineFilterCall(strings.TrimSpace(segment))
+			if err != nil {
+				return source, nil
+			}
+			modifiers = append(modifiers, modifier)
+		}
+		return strings.TrimSpace(strings.Join(segments[:sourceEnd], "|")), modifiers
+	}
+	return source, nil
+}
+
+// applyModifiers applies the range modifiers to the items in order. The
+// arguments of the modifiers are evaluated using the node's scope, except
+// for the condition of `where` which is evaluated using the scope of each
+// item. valueType is the type of the values of the items, which is used
+// for the slices made by `chunk` and `groupBy`.
+func (re *RangeExtension) applyModifiers(
+	node *Node, dependencies ExtensionDependencies, params EvaluatorParams,
+	items []rangeItem, valueType reflect.Type,
+) ([]rangeItem, error) {
+	for _, modifier := range re.modifiers {
+		var err error
+		if modifier.name == "where" {
+			items, err = re.whereModifier(node, dependencies, params, items, modifier.args)
+		} else {
+			args := make([]interface{}, len(modifier.args))
+			for argIdx, arg := range modifier.args {
+				if args[argIdx], err = evaluatePipeline(node, dependencies, arg, params); err != nil {
+					return nil, err
+				}
+			}
+			items, valueType, err = applyRangeModifier(modifier.name, items, valueType, args)
+		}
+		if err != nil {
+			var limitErr *LimitError
+			if errors.As(err, &limitErr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("modifier `%v`: %v", modifier.name, err)
+		}
+	}
+	return items, nil
+}
+
+func (re *RangeExtension) whereModifier(
+	node *Node, dependencies ExtensionDependencies, params EvaluatorParams,
+	items []rangeItem, args []string,
+) ([]rangeItem, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expects 1 argument(s), got %d", len(args))
+	}
+	var matches []rangeItem
+	for _, item := range items {
+		itemParams, err := re.itemParams(item)
+		if err != nil {
+			return nil, err
+		}
+		itemScope := &Node{
+			Data:          node.Data,
+			Type:          node.Type,
+			position:      node.position,
+			contextParams: itemParams,
+			parentECS:     node.scopeSource(),
+		}
+		isMatch, err := evaluateBoolPipeline(itemScope, dependencies, args[0], params)
+		if err != nil {
+			return nil, err
+		} else if isMatch {
+			matches = append(matches, item)
+		}
+	}
+	return matches, nil
+}
+
+func applyRangeModifier(
+	name string, items []rangeItem, valueType reflect.Type, args []interface{},
+) ([]rangeItem, reflect.Type, error) {
+	switch name {
+	case "chunk":
+		if err := expectFilterArgs(args, 1, 1); err != nil {
+			return nil, nil, err
+		}
+		size, err := toInt(args[0])
+		if err != nil {
+			return nil, nil, err
+		} else if size <= 0 {
+			return nil, nil, errors.New("chunk size must be positive")
+		}
+		var chunks []rangeItem
+		for start := 0; start < len(items); start += size {
+			end := start + size
+			if end > len(items) {
+				end = len(items)
+			}
+			chunks = append(chunks, rangeItem{
+				key:   len(chunks),
+				value: rangeItemValues(items[start:end], valueType),
+			})
+		}
+		return chunks, reflect.SliceOf(valueType), nil
+	case "groupBy":
+		if err := expectFilterArgs(args, 1, 1); err != nil {
+			return nil, nil, err
+		}
+		field, err := FormatValue(args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		var groupKeys []interface{}
+		var groupItems [][]rangeItem
+	itemsLoop:
+		for _, item := range items {
+			key, err := lookupField(item.value, field)
+			if err != nil {
+				return nil, nil, err
+			}
+			for groupIdx, groupKey := range groupKeys {
+				if valuesEqual(groupKey, key) {
+					groupItems[groupIdx] = append(groupItems[groupIdx], item)
+					continue itemsLoop
+				}
+			}
+			groupKeys = append(groupKeys, key)
+			groupItems = append(groupItems, []rangeItem{item})
+		}
+		groups := make([]rangeItem, len(groupKeys))
+		for groupIdx, groupKey := range groupKeys {
+			groups[groupIdx] = rangeItem{
+				key:   groupKey,
+				value: &RangeGroup{Key: groupKey, Items: rangeItemValues(groupItems[groupIdx], valueType)},
+				keyed: true,
+			}
+		}
+		return groups, rangeGroupType, nil
+	case "sortBy":
+		sorted, err := sortRangeItems(items, args)
+		return sorted, valueType, err
+	case "reverse":
+		if err := expectFilterArgs(args, 0, 0); err != nil {
+			return nil, nil, err
+		}
+		reversed := make([]rangeItem, len(items))
+		for itemIdx, item := range items {
+			reversed[len(items)-1-itemIdx] = item
+		}
+		return reversed, valueType, nil
+	case "limit", "offset":
+		if err := expectFilterArgs(args, 1, 1); err != nil {
+			return nil, nil, err
+		}
+		n, err := toInt(args[0])
+		if err != nil {
+			return nil, nil, err
+		} else if n < 0 {
+			return nil, nil, fmt.Errorf("%v must not be negative", name)
+		}
+		if n > len(items) {
+			n = len(items)
+		}
+		if name == "limit" {
+			return items[:n:n], valueType, nil
+		}
+		return items[n:], valueType, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown range modifier `%v`", name)
+	}
+}
+
+func sortRangeItems(items []rangeItem, args []interface{}) ([]rangeItem, error) {
+	field, descending, err := sortByArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, len(items))
+	for itemIdx, item := range items {
+		if keys[itemIdx], err = lookupField(item.value, field); err != nil {
+			return nil, err
+		}
+	}
+	indexes, err := sortPermutation(keys, descending)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]rangeItem, len(items))
+	for i, index := range indexes {
+		sorted[i] = items[index]
+	}
+	return sorted, nil
+}
+
+// rangeItemValues makes a slice of the values of the items.
+func rangeItemValues(items []rangeItem, valueType reflect.Type) interface{} {
+	values := reflect.MakeSlice(reflect.SliceOf(valueType), len(items), len(items))
+	for itemIdx, item := range items {
+		if item.value != nil {
+			values.Index(itemIdx).Set(reflect.ValueOf(item.value))
+		}
+	}
+	return values.Interface()
+}
+
+// rangeModifierTypes is the static counterpart of applyModifiers. It
+// returns the types of the keys and the values of the items after the
+// modifiers are applied. Nil types are unknown.
+func rangeModifierTypes(
+	modifiers []pipelineFilterCall, keyType, valueType reflect.Type, keyed bool,
+) (reflect.Type, reflect.Type, bool) {
+	for _, modifier := range modifiers {
+		switch modifier.name {
+		case "chunk":
+			keyType, keyed = intType, false
+			if valueType != nil {
+				valueType = reflect.SliceOf(valueType)
+			}
+		case "groupBy":
+			keyType, valueType, keyed = nil, rangeGroupType, true
+		}
+	}
+	return keyType, valueType, keyed
+}
@@ -0,0 +1,248 @@
+package tplinator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func indirectValue(rv reflect.Value) (reflect.Value, bool) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return rv, false
+		}
+		rv = rv.Elem()
+	}
+	return rv, rv.IsValid()
+}
+
+func isExportedName(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// methodByName looks for the method on both the value and a pointer to
+// it so that methods with pointer receivers can be called on values that
+// were not passed as pointers. A method with a value receiver cannot be
+// called on a nil pointer.
+func methodByName(rv reflect.Value, name string) (reflect.Value, bool, error) {
+	if !isExportedName(name) {
+		return reflect.Value{}, false, nil
+	}
+	for {
+		if method := rv.MethodByName(name); method.IsValid() {
+			if rv.Kind() == reflect.Ptr && rv.IsNil() {
+				if _, hasValueMethod := rv.Type().Elem().MethodByName(name); hasValueMethod {
+					return reflect.Value{}, false, fmt.Errorf("cannot call `%v` on a nil %v", name, rv.Type())
+				}
+			}
+			return method, true, nil
+		}
+		if rv.Kind() != reflect.Ptr && rv.Kind() != reflect.Interface {
+			break
+		}
+		if rv.IsNil() {
+			return reflect.Value{}, false, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return reflect.Value{}, false, nil
+	}
+	if rv.CanAddr() {
+		rv = rv.Addr()
+	} else {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		rv = ptr
+	}
+	method := rv.MethodByName(name)
+	return method, method.IsValid(), nil
+}
+
+type memberNotFoundError struct {
+	name  string
+	value interface{}
+}
+
+func (e memberNotFoundError) Error() string {
+	return fmt.Sprintf("`%v` was not found on %T", e.name, e.value)
+}
+
+func resolveMember(value interface{}, name string) (interface{}, error) {
+	if value == nil {
+		return nil, fmt.Errorf("cannot access `%v` of nil", name)
+	}
+
+	rv := reflect.ValueOf(value)
+	if method, hasMethod, err := methodByName(rv, name); err != nil {
+		return nil, err
+	} else if hasMethod {
+		return method.Interface(), nil
+	}
+
+	rv, isValid := indirectValue(rv)
+	if !isValid {
+		return nil, fmt.Errorf("cannot access `%v` of a nil %T", name, value)
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		keyType := rv.Type().Key()
+		if keyType.Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot access `%v` of %T because its keys are not strings", name, value)
+		}
+		member := rv.MapIndex(reflect.ValueOf(name).Convert(keyType))
+		if !member.IsValid() {
+			return nil, memberNotFoundError{name: name, value: value}
+		}
+		return member.Interface(), nil
+	case reflect.Struct:
+		field, hasField := structFieldByName(rv.Type(), name)
+		if !hasField {
+			if !isExportedName(name) {
+				return nil, fmt.Errorf("cannot access the unexported field or method `%v` of %T", name, value)
+			}
+			return nil, fmt.Errorf("%T does not have a field or method named `%v`", value, name)
+		}
+		member, isPromotedFromNil := fieldByIndex(rv, field.Index)
+		if isPromotedFromNil {
+			return nil, fmt.Errorf("cannot access `%v` of %T because it is promoted from a nil embedded pointer", name, value)
+		}
+		return member.Interface(), nil
+	default:
+		return nil, fmt.Errorf("cannot access `%v` of %T", name, value)
+	}
+}
+
+// fieldByIndex is reflect.Value#FieldByIndex without the panic when the
+// field is promoted from an embedded pointer that is nil.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for indexIdx, fieldIdx := range index {
+		if indexIdx > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, true
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(fieldIdx)
+	}
+	return rv, false
+}
+
+// structFieldByName looks for an exported field using its name or the
+// name in its `json` tag.
+func structFieldByName(structType reflect.Type, name string) (reflect.StructField, bool) {
+	if isExportedName(name) {
+		if field, hasField := structType.FieldByName(name); hasField {
+			return field, true
+		}
+	}
+	for _, field := range reflect.VisibleFields(structType) {
+		if field.IsExported() && !field.Anonymous && jsonFieldName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "" || tag == "-" {
+		return ""
+	}
+	if commaIdx := strings.IndexByte(tag, ','); commaIdx >= 0 {
+		tag = tag[:commaIdx]
+	}
+	return tag
+}
+
+func ValueParams(value interface{}) (EvaluatorParams, error) {
+	if params, isParams := value.(EvaluatorParams); isParams {
+		return params, nil
+	}
+
+	rv, isValid := indirectValue(reflect.ValueOf(value))
+	if !isValid {
+		return nil, fmt.Errorf("cannot use a nil %T as params", value)
+	}
+
+	params := make(EvaluatorParams)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot use %T as params because its keys are not strings", value)
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			params[iter.Key().String()] = iter.Value().Interface()
+		}
+		return params, nil
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(rv.Type()) {
+			if !field.IsExported() {
+				continue
+			}
+			fieldValue, isPromotedFromNil := fieldByIndex(rv, field.Index)
+			if isPromotedFromNil {
+				continue
+			}
+			params[field.Name] = fieldValue.Interface()
+			if jsonName := jsonFieldName(field); jsonName != "" {
+				params[jsonName] = fieldValue.Interface()
+			}
+		}
+
+		receiver := rv
+		if value := reflect.ValueOf(value); value.Kind() == reflect.Ptr {
+			receiver = value
+		}
+		if receiver.Kind() != reflect.Ptr {
+			receiver = reflect.New(rv.Type())
+			receiver.Elem().Set(rv)
+		}
+		for methodIdx := 0; methodIdx < receiver.NumMethod(); methodIdx++ {
+			params[receiver.Type().Method(methodIdx).Name] = receiver.Method(methodIdx).Interface()
+		}
+		return params, nil
+	default:
+		return nil, fmt.Errorf("cannot use %T as params", value)
+	}
+}
+
+func resolvePath(value interface{}, path []string) (interface{}, error) {
+	for pathIdx, name := range path {
+		member, err := resolveMember(value, name)
+		if err != nil {
+			if pathIdx > 0 {
+				return nil, fmt.Errorf("%v: %w", strings.Join(path[:pathIdx], "."), err)
+			}
+			return nil, err
+		}
+		value = member
+	}
+	return value, nil
+}
+
+func callMethod(receiver interface{}, name string, args []interface{}) (interface{}, error) {
+	if receiver == nil {
+		return nil, fmt.Errorf("cannot call `%v` of nil", name)
+	}
+	method, hasMethod, err := methodByName(reflect.ValueOf(receiver), name)
+	if err != nil {
+		return nil, err
+	}
+	if !hasMethod {
+		if !isExportedName(name) {
+			return nil, fmt.Errorf("cannot call the unexported method `%v` of %T", name, receiver)
+		}
+		member, err := resolveMember(receiver, name)
+		if err != nil {
+			return nil, err
+		}
+		method = reflect.ValueOf(member)
+	}
+	return callFunc(name, method, args)
+}
@@ -0,0 +1,166 @@
+package tplinator_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+type auditInfo struct {
+	CreatedBy string
+}
+
+type profile struct {
+	DisplayName string
+	nickname    string
+}
+
+type user struct {
+	auditInfo
+
+	Name    string
+	Profile *profile
+	Roles   []string
+	Address map[string]interface{}
+
+	password string
+}
+
+func (u *user) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (u user) Greeting(greeting string) string {
+	return greeting + ", " + u.Name
+}
+
+func (u user) secret() string {
+	return u.password
+}
+
+type moderator struct {
+	*auditInfo
+
+	Level int
+}
+
+type order struct {
+	Total  int
+	Status string
+	Owner  *user
+}
+
+func (o order) IsPaid() bool {
+	return o.Status == "paid"
+}
+
+func (o *order) Validate() (bool, error) {
+	if o.Total < 0 {
+		return false, errors.New("negative total")
+	}
+	return true, nil
+}
+
+func TestMemberAccess(t *testing.T) {
+	larry := &user{
+		auditInfo: auditInfo{CreatedBy: "perry"},
+		Name:      "Larry",
+		Profile:   &profile{DisplayName: "Larry the Dog", nickname: "lar"},
+		Roles:     []string{"admin"},
+		Address: map[string]interface{}{
+			"city": map[string]interface{}{"name": "Manila"},
+		},
+		password: "hunter2",
+	}
+	params := tplinator.EvaluatorParams{
+		"user":     larry,
+		"order":    order{Total: 1999, Status: "paid", Owner: larry},
+		"unpaid":   &order{Total: -1, Status: "pending"},
+		"settings": tplinator.EvaluatorParams{"theme": map[string]string{"color": "teal"}},
+		"nobody":   (*user)(nil),
+		"mod":      moderator{Level: 2},
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+
+		expected      interface{}
+		expectedError string
+	}{
+		{name: "pointer field", input: "user.Profile.DisplayName", expected: "Larry the Dog"},
+		{name: "embedded field", input: "user.CreatedBy", expected: "perry"},
+		{name: "nested pointer", input: "order.Owner.Profile.DisplayName", expected: "Larry the Dog"},
+		{name: "map in map", input: "user.Address.city.name", expected: "Manila"},
+		{name: "params in params", input: "settings.theme.color", expected: "teal"},
+		{name: "method", input: "order.IsPaid()", expected: true},
+		{name: "pointer receiver on value", input: "order.Validate()", expected: true},
+		{name: "method with arguments", input: "user.HasRole('admin') && !user.HasRole('owner')", expected: true},
+		{name: "method on nested value", input: "order.Owner.Greeting('Hi')", expected: "Hi, Larry"},
+		{name: "method in a comparison", input: "order.Total > 1000 && order.IsPaid()", expected: true},
+		{name: "method argument path", input: "user.Greeting(user.Profile.DisplayName)", expected: "Larry the Dog, Larry"},
+		{name: "method error", input: "unpaid.Validate()", expectedError: "negative total"},
+		{name: "unexported field", input: "user.password", expectedError: "unexported"},
+		{name: "unexported nested field", input: "user.Profile.nickname", expectedError: "unexported"},
+		{name: "unexported method", input: "user.secret()", expectedError: "unexported"},
+		{name: "unknown field", input: "user.Age", expectedError: "does not have a field or method named `Age`"},
+		{name: "missing map key", input: "settings.font", expectedError: "missing variable `settings.font`"},
+		{name: "nil pointer", input: "nobody.Name", expectedError: "nil"},
+		{name: "nil embedded pointer", input: "mod.CreatedBy", expectedError: "cannot access `CreatedBy` of tplinator_test.moderator because it is promoted from a nil embedded pointer"},
+		{name: "value receiver on a nil pointer", input: "nobody.Greeting('Hi')", expectedError: "cannot call `Greeting` on a nil *tplinator_test.user"},
+	}
+
+	evaluators := map[string]tplinator.Evaluator{
+		"govaluate": tplinator.NewGovaluateEvaluator(),
+		"native":    tplinator.NewNativeEvaluator(),
+	}
+	for evaluatorName, evaluator := range evaluators {
+		for _, tc := range testCases {
+			t.Run(evaluatorName+"/"+tc.name, func(t *testing.T) {
+				actual, err := evaluator.Evaluate(tc.input, params)
+				if tc.expectedError != "" {
+					if err == nil {
+						t.Errorf("expecting an error, got `%v`", actual)
+					} else if !strings.Contains(err.Error(), tc.expectedError) {
+						t.Errorf("wanted an error containing `%v`, got `%v`", tc.expectedError, err)
+					}
+				} else if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				} else if actual != tc.expected {
+					t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+				}
+			})
+		}
+	}
+}
+
+func TestMemberAccess_Template(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<div><h1 go-if="order.IsPaid()">{{go:order.Owner.Profile.DisplayName | upper}}</h1>` +
+			`<p go-range="users | sortBy('Profile.DisplayName')">{{go:name}}</p></div>`,
+	))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{
+		"order": &order{Status: "paid", Owner: &user{Profile: &profile{DisplayName: "Larry"}}},
+		"users": tplinator.RangeParams(
+			tplinator.EvaluatorParams{"name": "b", "Profile": profile{DisplayName: "Perry"}},
+			tplinator.EvaluatorParams{"name": "a", "Profile": &profile{DisplayName: "Larry"}},
+		),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<div><h1>LARRY</h1><p>a</p><p>b</p></div>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+}
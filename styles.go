@@ -0,0 +1,234 @@
+package tplinator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var stylePropertyRegex = regexp.MustCompile(`^-{0,2}[A-Za-z_][A-Za-z0-9_-]*$`)
+
+type styleDeclaration struct {
+	property string
+	value    string
+}
+
+type conditionalStyle struct {
+	property   string
+	expression string
+}
+
+// StyleBindingExtension sets the properties of the `style` attribute of an
+// element to the values of `go-if-style-*` expressions and of the map that
+// the `go-style` expression evaluates to. They replace the properties of
+// the static `style` attribute that have the same name. A property whose
+// value is nil or false keeps its static value, or is removed if it does
+// not have one.
+type StyleBindingExtension struct {
+	originalStyles    []styleDeclaration
+	conditionalStyles []conditionalStyle
+	styleExpression   string
+}
+
+func (sbe *StyleBindingExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
+	styles := make([]styleDeclaration, len(sbe.originalStyles))
+	copy(styles, sbe.originalStyles)
+
+	for _, conditionalStyle := range sbe.conditionalStyles {
+		value, err := evaluatePipeline(node, dependencies, conditionalStyle.expression, params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("style ext: %w", err)
+		}
+		if styles, err = sbe.setStyle(dependencies, styles, conditionalStyle.property, value); err != nil {
+			return nil, nil, fmt.Errorf("style ext: `%v`: %v", conditionalStyle.expression, err)
+		}
+	}
+	if sbe.styleExpression != "" {
+		value, err := evaluatePipeline(node, dependencies, sbe.styleExpression, params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("style ext: %w", err)
+		}
+		if styles, err = sbe.setStyleMap(dependencies, styles, value); err != nil {
+			return nil, nil, fmt.Errorf("style ext: `%v`: %v", sbe.styleExpression, err)
+		}
+	}
+
+	copyNode := CopyNode(node)
+	if len(styles) > 0 {
+		declarations := make([]string, len(styles))
+		for styleIdx, style := range styles {
+			if style.property == "" {
+				declarations[styleIdx] = style.value
+			} else {
+				declarations[styleIdx] = style.property + ": " + style.value
+			}
+		}
+		copyNode.AddAttribute("style", strings.Join(declarations, "; "))
+	}
+	return copyNode, nil, nil
+}
+
+func (sbe *StyleBindingExtension) Expressions() []string {
+	var expressions []string
+	for _, conditionalStyle := range sbe.conditionalStyles {
+		expressions = append(expressions, conditionalStyle.expression)
+	}
+	if sbe.styleExpression != "" {
+		expressions = append(expressions, sbe.styleExpression)
+	}
+	return expressions
+}
+
+// setStyleMap sets the properties of the styles to the values of the map.
+// The properties are set in the order of their names because the order of
+// the keys of a map is not known.
+func (sbe *StyleBindingExtension) setStyleMap(dependencies ExtensionDependencies, styles []styleDeclaration, value interface{}) ([]styleDeclaration, error) {
+	if _, isMissing := value.(missingValue); isMissing || value == nil {
+		return styles, nil
+	}
+	mapValue := reflect.ValueOf(value)
+	if mapValue.Kind() != reflect.Map || mapValue.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("expecting a map of style properties, got %T", value)
+	}
+
+	properties := make([]string, 0, mapValue.Len())
+	for _, key := range mapValue.MapKeys() {
+		properties = append(properties, key.String())
+	}
+	sort.Strings(properties)
+
+	var err error
+	for _, property := range properties {
+		propertyValue := mapValue.MapIndex(reflect.ValueOf(property).Convert(mapValue.Type().Key())).Interface()
+		if styles, err = sbe.setStyle(dependencies, styles, property, propertyValue); err != nil {
+			return nil, err
+		}
+	}
+	return styles, nil
+}
+
+// setStyle replaces the value of the property, or adds the property if it
+// does not have a value yet. If the value is nil, false or a missing
+// variable, the property is set back to its static value or is removed.
+func (sbe *StyleBindingExtension) setStyle(dependencies ExtensionDependencies, styles []styleDeclaration, property string, value interface{}) ([]styleDeclaration, error) {
+	property = strings.ToLower(strings.TrimSpace(property))
+	if !stylePropertyRegex.MatchString(property) {
+		return nil, fmt.Errorf("`%v` is not a valid style property", property)
+	}
+
+	isRemoved := value == nil || value == false
+	if missing, isMissing := value.(missingValue); isMissing {
+		isRemoved = missing.policy != MissingKeyPlaceholder
+	}
+	var formattedValue string
+	if !isRemoved {
+		var err error
+		if formattedValue, err = formatValue(dependencies, value); err != nil {
+			return nil, err
+		}
+		formattedValue = escapeStyleValue(formattedValue)
+	}
+
+	if isRemoved {
+		for _, original := range sbe.originalStyles {
+			if original.property == property {
+				isRemoved, formattedValue = false, original.value
+			}
+		}
+	}
+
+	for styleIdx, style := range styles {
+		if style.property != property {
+			continue
+		}
+		if isRemoved {
+			return append(styles[:styleIdx:styleIdx], styles[styleIdx+1:]...), nil
+		}
+		styles[styleIdx].value = formattedValue
+		return styles, nil
+	}
+	if isRemoved {
+		return styles, nil
+	}
+	return append(styles, styleDeclaration{property: property, value: formattedValue}), nil
+}
+
+// escapeStyleValue escapes the characters that could end the declaration,
+// the rule or the attribute that a value is in using CSS escapes.
+func escapeStyleValue(value string) string {
+	var builder strings.Builder
+	for _, r := range strings.TrimSpace(value) {
+		switch {
+		case r < ' ' || r == 0x7f, strings.ContainsRune(`;{}\"'<>&`, r):
+			fmt.Fprintf(&builder, `\%x `, r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// parseStyleDeclarations splits the value of a `style` attribute into its
+// declarations. The declarations that do not have a property are kept as
+// is.
+func parseStyleDeclarations(style string) []styleDeclaration {
+	declarations, err := splitTopLevel(style, ';')
+	if err != nil {
+		declarations = []string{style}
+	}
+	var styles []styleDeclaration
+	for _, declaration := range declarations {
+		declaration = strings.TrimSpace(declaration)
+		if declaration == "" {
+			continue
+		}
+		colonIdx := strings.IndexByte(declaration, ':')
+		if colonIdx < 0 {
+			styles = append(styles, styleDeclaration{value: declaration})
+			continue
+		}
+		styles = append(styles, styleDeclaration{
+			property: strings.ToLower(strings.TrimSpace(declaration[:colonIdx])),
+			value:    strings.TrimSpace(declaration[colonIdx+1:]),
+		})
+	}
+	return styles
+}
+
+// StyleBindingExtensionNodeProcessor adds the StyleBindingExtension to the
+// elements that have `go-if-style-*` or `go-style` attributes.
+func StyleBindingExtensionNodeProcessor(node *Node) {
+	styleBindingExtension := &StyleBindingExtension{}
+
+	ifStyleAttrs := node.HasAttributes(func(attr Attribute) bool {
+		return strings.HasPrefix(attr.Key, "go-if-style-")
+	})
+	for _, ifStyleAttr := range ifStyleAttrs {
+		property := strings.TrimSpace(strings.TrimPrefix(ifStyleAttr.Key, "go-if-style-"))
+		if property != "" {
+			styleBindingExtension.conditionalStyles = append(
+				styleBindingExtension.conditionalStyles,
+				conditionalStyle{
+					property:   property,
+					expression: strings.TrimSpace(ifStyleAttr.Value),
+				},
+			)
+			node.RemoveAttribute(ifStyleAttr.Key)
+		}
+	}
+	if hasStyle, _, expression := node.HasAttribute("go-style"); hasStyle {
+		styleBindingExtension.styleExpression = strings.TrimSpace(expression)
+		node.RemoveAttribute("go-style")
+	}
+	if len(styleBindingExtension.conditionalStyles) == 0 && styleBindingExtension.styleExpression == "" {
+		return
+	}
+
+	if hasStyle, _, style := node.HasAttribute("style"); hasStyle {
+		styleBindingExtension.originalStyles = parseStyleDeclarations(style)
+		node.RemoveAttribute("style")
+	}
+	node.AddExtension(styleBindingExtension)
+}
@@ -0,0 +1,204 @@
+package tplinator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// switchSubjectParam is the name of the value of the subject of a
+// `go-switch` in the scope of the element's children.
+const switchSubjectParam = "$switch"
+
+// SwitchExtension evaluates the subject of an element with a `go-switch`
+// attribute once so that its `go-case` children can be compared with it.
+type SwitchExtension struct {
+	subject string
+}
+
+func (se *SwitchExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
+	subject, err := evaluatePipeline(node, dependencies, se.subject, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, isMissing := subject.(missingValue); isMissing {
+		subject = nil
+	}
+
+	// the node might be a copy made by another extension that already has
+	// its own params (e.g. RangeExtension)
+	contextParams := EvaluatorParams{switchSubjectParam: subject}
+	for key, value := range node.contextParams {
+		if _, isSubject := contextParams[key]; !isSubject {
+			contextParams[key] = value
+		}
+	}
+	nodeCopy := CopyNode(node)
+	nodeCopy.SetContextParams(contextParams)
+	return nodeCopy, nil, nil
+}
+
+func (se *SwitchExtension) Expressions() []string {
+	return []string{se.subject}
+}
+
+type switchCase struct {
+	node *Node
+	// values are the expressions of a `go-case`. They are nil for the
+	// `go-default`.
+	values []string
+}
+
+// SwitchCaseExtension renders the first of the `go-case` children of a
+// `go-switch` element that has a value equal to the subject, or the
+// `go-default` child if none of them has one. It is added to the first of
+// the children and the others are removed from the tree.
+type SwitchCaseExtension struct {
+	cases []switchCase
+}
+
+func (sce *SwitchCaseExtension) Apply(node *Node, dependencies ExtensionDependencies, params EvaluatorParams) (*Node, []*Node, error) {
+	subject, inSwitch := switchSubject(node)
+	if !inSwitch {
+		return nil, nil, fmt.Errorf("switch ext: %v: `go-case` must be a child of a `go-switch`", node.Position())
+	}
+
+	for caseIdx, c := range sce.cases {
+		isMatch := c.values == nil
+		for _, valueExpression := range c.values {
+			value, err := evaluatePipeline(c.node, dependencies, valueExpression, params)
+			if err != nil {
+				return nil, nil, err
+			}
+			if valuesEqual(subject, value) {
+				isMatch = true
+				break
+			}
+		}
+		if !isMatch {
+			continue
+		}
+		// the first case belongs to the node itself, which might be a copy
+		// made by another extension
+		if caseIdx == 0 {
+			return node, nil, nil
+		}
+		branches, err := applyBranch(node, c.node, dependencies, params)
+		return nil, branches, err
+	}
+	return nil, nil, nil
+}
+
+func (sce *SwitchCaseExtension) Expressions() []string {
+	var expressions []string
+	for _, c := range sce.cases {
+		expressions = append(expressions, c.values...)
+	}
+	return expressions
+}
+
+// switchSubject returns the subject of the innermost `go-switch` that the
+// node is in.
+func switchSubject(node *Node) (interface{}, bool) {
+	for _, contextParams := range node.GetContextParams() {
+		if subject, hasSubject := contextParams[switchSubjectParam]; hasSubject {
+			return subject, true
+		}
+	}
+	return nil, false
+}
+
+// SwitchExtensionNodeProcessor adds the SwitchExtension to the elements
+// that have a `go-switch` attribute.
+func SwitchExtensionNodeProcessor(node *Node) {
+	if hasSwitch, _, subject := node.HasAttribute("go-switch"); hasSwitch {
+		node.AddExtension(&SwitchExtension{subject: strings.TrimSpace(subject)})
+		node.RemoveAttribute("go-switch")
+	}
+}
+
+// SwitchCaseExtensionNodeProcessor adds the SwitchCaseExtension to the
+// first of the `go-case` and `go-default` children of a `go-switch`
+// element and removes the other ones from the tree. The cases must be
+// next to each other since the one that matches is rendered in place of
+// all of them. It must come before the other node processors so that the
+// extensions of the case are only applied if it matches.
+func SwitchCaseExtensionNodeProcessor(node *Node) {
+	if !isSwitchCase(node) {
+		return
+	}
+	if parent := node.Parent(); parent == nil || !hasSwitchExtension(parent) {
+		addInvalidDirective(node, "`go-case` and `go-default` must be children of a `go-switch`")
+		return
+	}
+	if node.PreviousSibling() != nil && isSwitchCase(node.PreviousSibling()) {
+		return
+	}
+
+	switchCaseExtension := &SwitchCaseExtension{}
+	addCase := func(caseNode *Node) bool {
+		if hasCase, _, values := caseNode.HasAttribute("go-case"); hasCase {
+			caseNode.RemoveAttribute("go-case")
+			valueExpressions, err := splitTopLevel(values, ',')
+			if err != nil {
+				// keep the values as is so that evaluating them reports the error
+				valueExpressions = []string{values}
+			}
+			for valueIdx := range valueExpressions {
+				valueExpressions[valueIdx] = strings.TrimSpace(valueExpressions[valueIdx])
+			}
+			switchCaseExtension.cases = append(switchCaseExtension.cases, switchCase{
+				node: caseNode, values: valueExpressions,
+			})
+			return false
+		}
+		caseNode.RemoveAttribute("go-default")
+		switchCaseExtension.cases = append(switchCaseExtension.cases, switchCase{node: caseNode})
+		return true
+	}
+
+	if isDefault := addCase(node); !isDefault {
+		consumeBranchSiblings(node, func(sibling *Node) (bool, bool) {
+			if !isSwitchCase(sibling) {
+				return false, true
+			}
+			isDefault := addCase(sibling)
+			return true, isDefault
+		})
+	}
+	node.AddExtension(switchCaseExtension)
+
+	// the siblings are processed in any order, so the cases that were left
+	// might already have their own extension
+	node.NextSiblings(func(sibling *Node) bool {
+		if isSwitchCase(sibling) || hasSwitchCaseExtension(sibling) {
+			addInvalidDirective(node, "the `go-case` and `go-default` children of a `go-switch` "+
+				"must be next to each other, with the `go-default` last")
+			return false
+		}
+		return true
+	})
+}
+
+func isSwitchCase(node *Node) bool {
+	hasCase, _, _ := node.HasAttribute("go-case")
+	hasDefault, _, _ := node.HasAttribute("go-default")
+	return hasCase || hasDefault
+}
+
+func hasSwitchCaseExtension(node *Node) bool {
+	for _, extension := range node.extensions {
+		if _, isSwitchCase := extension.(*SwitchCaseExtension); isSwitchCase {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSwitchExtension(node *Node) bool {
+	for _, extension := range node.extensions {
+		if _, isSwitch := extension.(*SwitchExtension); isSwitch {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package tplinator
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"strings"
 
@@ -14,12 +15,22 @@ type Template struct {
 }
 
 func CreateTemplateFromReader(reader io.Reader, parserOptions ...ParserOptionFunc) (*Template, error) {
-	rootNodes, err := ParseNodes(reader, parserOptions...)
+	parser, err := newParser(reader, parserOptions...)
+	if err != nil {
+		return nil, err
+	}
+	rootNodes, err := parser.parse()
 	if err != nil {
 		return nil, err
 	}
 	return &Template{
 		rootNodes: rootNodes,
+		extDeps: compoundExtensionDependencies{
+			funcs:      parser.funcs,
+			evaluator:  parser.evaluator,
+			missingKey: parser.missingKey,
+			limits:     parser.limits,
+		},
 	}, nil
 }
 
@@ -27,6 +38,14 @@ func (tpl *Template) AddExtensionDependencies(extDeps ...ExtensionDependencies)
 	tpl.extDeps.extDeps = append(tpl.extDeps.extDeps, extDeps...)
 }
 
+func (tpl *Template) AddFuncs(funcs FuncMap) error {
+	if err := validateFuncMap(funcs); err != nil {
+		return fmt.Errorf("template: %v", err)
+	}
+	tpl.extDeps.funcs = mergeFuncMaps(funcs, tpl.extDeps.funcs)
+	return nil
+}
+
 func (tpl *Template) RenderBytes(params EvaluatorParams) ([]byte, error) {
 	var bb bytes.Buffer
 	if err := tpl.Render(params, func(str string) {
@@ -47,48 +66,92 @@ func (tpl *Template) RenderString(params EvaluatorParams) (string, error) {
 	return sb.String(), nil
 }
 
+func (tpl *Template) RenderValue(w io.Writer, value interface{}) error {
+	params, err := ValueParams(value)
+	if err != nil {
+		return fmt.Errorf("template: %v", err)
+	}
+
+	var writeErr error
+	err = tpl.Render(params, func(str string) {
+		if writeErr == nil {
+			_, writeErr = io.WriteString(w, str)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
 func (tpl *Template) Render(params EvaluatorParams, writerFunc func(string)) error {
 	type tplStartTag struct {
+		node  *Node
+		tag   string
+		depth int
+	}
+
+	type tplEndTag struct {
 		node *Node
 		tag  string
 	}
 
-	type tplEndTag struct {
-		tag string
+	budget := &renderBudget{limits: renderLimits(&tpl.extDeps)}
+	if budget.limits.MaxOperations > 0 && !countsOperations(tpl.extDeps.Get(EvaluatorExtDepKey)) {
+		return fmt.Errorf("template: MaxOperations needs an evaluator that counts the operations, e.g. NewNativeEvaluator()")
+	}
+	extDeps := renderExtensionDependencies{
+		ExtensionDependencies: &tpl.extDeps,
+		budget:                budget,
+	}
+	write := func(node *Node, str string) error {
+		if err := budget.useOutputBytes(node, len(str)); err != nil {
+			return err
+		}
+		writerFunc(str)
+		return nil
 	}
 
 	tagStack := stackgo.NewStack()
 
-	pushNode := func(node *Node) {
+	pushNode := func(node *Node, depth int) {
 		st, et := node.Tags()
 		if et != "" {
-			tagStack.Push(tplEndTag{tag: et})
+			tagStack.Push(tplEndTag{node: node, tag: et})
 		}
-		tagStack.Push(tplStartTag{node: node, tag: st})
+		tagStack.Push(tplStartTag{node: node, tag: st, depth: depth})
 	}
-	applyNodeExts := func(node *Node) error {
-		node, sibs, err := node.ApplyExtensions(&tpl.extDeps, params)
+	applyNodeExts := func(node *Node, depth int) error {
+		if err := budget.useDepth(node, depth); err != nil {
+			return err
+		}
+		node, sibs, err := node.ApplyExtensions(extDeps, params)
 		if err != nil {
 			return err
 		}
 		for i := len(sibs) - 1; i >= 0; i-- {
-			pushNode(sibs[i])
+			pushNode(sibs[i], depth)
 		}
 		if node != nil {
-			pushNode(node)
+			pushNode(node, depth)
 		}
 		return nil
 	}
 
 	for _, rootNode := range tpl.rootNodes {
-		err := applyNodeExts(rootNode)
+		err := applyNodeExts(rootNode, 1)
 		if err != nil {
 			return err
 		}
 		for tagStack.Top() != nil {
 			switch tag := tagStack.Pop().(type) {
 			case tplStartTag:
-				writerFunc(tag.tag)
+				// the start tag of a virtual element is empty
+				if tag.tag != "" {
+					if err := write(tag.node, tag.tag); err != nil {
+						return err
+					}
+				}
 
 				var children []*Node
 				tag.node.Children(func(_ int, child *Node) bool {
@@ -96,13 +159,15 @@ func (tpl *Template) Render(params EvaluatorParams, writerFunc func(string)) err
 					return true
 				})
 				for i := len(children) - 1; i >= 0; i-- {
-					err := applyNodeExts(children[i])
+					err := applyNodeExts(children[i], tag.depth+1)
 					if err != nil {
 						return err
 					}
 				}
 			case tplEndTag:
-				writerFunc(tag.tag)
+				if err := write(tag.node, tag.tag); err != nil {
+					return err
+				}
 			}
 		}
 	}
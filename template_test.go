@@ -92,3 +92,126 @@ func TestTemplate_RenderString(t *testing.T) {
 		t.Log("expecting error due to evaluator:", err)
 	}
 }
+
+func TestTemplate_RenderVirtualElement(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<table><go-template go-range="row in rows"><tr><td>{{go:row}}</td></tr><tr class="spacer"></tr></go-template></table>` +
+			`<p>Hello<go-template go-if="isAdmin">, admin</go-template></p>`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{"rows": []int{1, 2}, "isAdmin": true})
+	expected := `<table><tr><td>1</td></tr><tr class="spacer"></tr><tr><td>2</td></tr><tr class="spacer"></tr></table>` +
+		`<p>Hello, admin</p>`
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+}
+
+type pageItem struct {
+	Title string  `json:"title"`
+	Price float64 `json:"price,omitempty"`
+	Tags  []pageTag
+}
+
+type pageTag struct {
+	Name string
+}
+
+type pageData struct {
+	Heading string `json:"heading"`
+	Items   []pageItem
+	User    *user `json:"-"`
+}
+
+func (pd *pageData) ItemCount() int {
+	return len(pd.Items)
+}
+
+func TestTemplate_RenderValue(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<div><h1 title="{{go:Heading}}">{{go:heading}} ({{go:ItemCount()}})</h1>` +
+			`<p go-if="User.HasRole('admin')">{{go:User.Name}}</p>` +
+			`<ul><li go-range="Items"><b>{{go:title}}</b><i go-range="Tags">{{go:Name}}</i></li></ul></div>`,
+	))
+	if err != nil {
+		t.Error("unexpected error:", err)
+		return
+	}
+
+	var sb strings.Builder
+	err = tpl.RenderValue(&sb, pageData{
+		Heading: "Menu",
+		Items: []pageItem{
+			{Title: "Cheesecake", Tags: []pageTag{{Name: "sweet"}, {Name: "cold"}}},
+			{Title: "Coffee"},
+		},
+		User: &user{Name: "Larry", Roles: []string{"admin"}},
+	})
+	if err != nil {
+		t.Error("unexpected error:", err)
+		return
+	}
+	expected := `<div><h1 title="Menu">Menu (2)</h1><p>Larry</p>` +
+		`<ul><li><b>Cheesecake</b><i>sweet</i><i>cold</i></li><li><b>Coffee</b></li></ul></div>`
+	if actual := sb.String(); actual != expected {
+		t.Error("unexpected result. actual:", actual,
+			"expected:", expected)
+	}
+
+	err = tpl.RenderValue(&sb, "not a struct")
+	if err == nil {
+		t.Error("expecting an error because a string cannot be used as params")
+	}
+	err = tpl.RenderValue(&sb, (*pageData)(nil))
+	if err == nil {
+		t.Error("expecting an error because the value is nil")
+	}
+}
+
+func TestValueParams(t *testing.T) {
+	params, err := tplinator.ValueParams(&pageData{
+		Heading: "Menu",
+		User:    &user{auditInfo: auditInfo{CreatedBy: "perry"}},
+	})
+	if err != nil {
+		t.Error("unexpected error:", err)
+		return
+	}
+	for _, key := range []string{"Heading", "heading", "Items", "User", "ItemCount"} {
+		if _, hasKey := params[key]; !hasKey {
+			t.Errorf("expecting the params to have `%v`", key)
+		}
+	}
+	if _, hasKey := params["-"]; hasKey {
+		t.Error("expecting the params to not have `-`")
+	}
+
+	params, err = tplinator.ValueParams(moderator{Level: 2})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	} else if _, hasKey := params["CreatedBy"]; hasKey || params["Level"] != 2 {
+		t.Errorf("expecting the params to only have the fields that are not promoted from nil, got %v", params)
+	}
+	params, err = tplinator.ValueParams(moderator{auditInfo: &auditInfo{CreatedBy: "perry"}})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	} else if params["CreatedBy"] != "perry" {
+		t.Error("expecting the params to have `CreatedBy`")
+	}
+
+	params, err = tplinator.ValueParams(map[string]int{"count": 1})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	} else if params["count"] != 1 {
+		t.Error("expecting the params to have `count`")
+	}
+	_, err = tplinator.ValueParams(map[int]string{1: "one"})
+	if err == nil {
+		t.Error("expecting an error because the keys of the map are not strings")
+	}
+}
@@ -5,9 +5,15 @@ import "io"
 func Tplinate(tplReader io.Reader, parserOptions ...ParserOptionFunc) (*Template, error) {
 	defaultParserOptions := []ParserOptionFunc{
 		NodeProcessorsParserOption(
+			SwitchCaseExtensionNodeProcessor,
 			ConditionalExtensionNodeProcessor,
 			RangeExtensionNodeProcessor,
+			SwitchExtensionNodeProcessor,
 			ConditionalClassExtensionNodeProcessor,
+			StyleBindingExtensionNodeProcessor,
+			AttributeBindingExtensionNodeProcessor,
+			TranslateExtensionNodeProcessor,
+			TextExtensionNodeProcessor,
 			StringInterpolationNodeProcessor,
 		),
 	}
@@ -17,9 +23,7 @@ func Tplinate(tplReader io.Reader, parserOptions ...ParserOptionFunc) (*Template
 	if err != nil {
 		return nil, err
 	}
-	template.extDeps = compoundExtensionDependencies{
-		defaultExtDep: NewDefaultExtensionDependencies(),
-	}
+	template.extDeps.defaultExtDep = NewDefaultExtensionDependencies()
 
 	return template, nil
 }
@@ -0,0 +1,109 @@
+package tplinator
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+type ValueFormatter interface {
+	FormatValue(value interface{}) (string, error)
+}
+
+type ValueFormatterFunc func(value interface{}) (string, error)
+
+func (vff ValueFormatterFunc) FormatValue(value interface{}) (string, error) {
+	return vff(value)
+}
+
+type DefaultValueFormatter struct {
+	TimeLayout string
+}
+
+func (dvf DefaultValueFormatter) FormatValue(value interface{}) (string, error) {
+	timeLayout := dvf.TimeLayout
+	if timeLayout == "" {
+		timeLayout = time.RFC3339
+	}
+
+	// the order of the cases matters. time.Time implements both
+	// fmt.Stringer and encoding.TextMarshaler but has its own rule,
+	// and fmt.Stringer wins over encoding.TextMarshaler.
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.Format(timeLayout), nil
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.Format(timeLayout), nil
+	case fmt.Stringer:
+		if isNilValue(v) {
+			return "", nil
+		}
+		return v.String(), nil
+	case encoding.TextMarshaler:
+		if isNilValue(v) {
+			return "", nil
+		}
+		text, err := v.MarshalText()
+		if err != nil {
+			return "", fmt.Errorf("formatter: %v", err)
+		}
+		return string(text), nil
+	case error:
+		if isNilValue(v) {
+			return "", nil
+		}
+		return v.Error(), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return "", nil
+		}
+		return dvf.FormatValue(rv.Elem().Interface())
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	case reflect.Complex64, reflect.Complex128:
+		return fmt.Sprint(value), nil
+	default:
+		return "", fmt.Errorf("formatter: cannot format a value of type %T", value)
+	}
+}
+
+func FormatValue(value interface{}) (string, error) {
+	return DefaultValueFormatter{}.FormatValue(value)
+}
+
+func isNilValue(value interface{}) bool {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
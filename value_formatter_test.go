@@ -0,0 +1,134 @@
+package tplinator_test
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+type temperature float64
+
+type celsius float64
+
+func (c celsius) String() string {
+	return strconv.FormatFloat(float64(c), 'f', 1, 64) + "°C"
+}
+
+func TestDefaultValueFormatter(t *testing.T) {
+	var nilTime *time.Time
+	var nilStringer *celsius
+	moment := time.Date(2018, time.September, 2, 13, 4, 5, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{name: "nil", value: nil, expected: ""},
+		{name: "string", value: "Someday", expected: "Someday"},
+		{name: "bytes", value: []byte("HalfNoise"), expected: "HalfNoise"},
+		{name: "int", value: 42, expected: "42"},
+		{name: "negative int64", value: int64(-7), expected: "-7"},
+		{name: "uint8", value: uint8(255), expected: "255"},
+		{name: "float64 from json", value: float64(3), expected: "3"},
+		{name: "float64 with fraction", value: 3.25, expected: "3.25"},
+		{name: "float32", value: float32(0.5), expected: "0.5"},
+		{name: "named float", value: temperature(36.6), expected: "36.6"},
+		{name: "bool", value: true, expected: "true"},
+		{name: "time", value: moment, expected: "2018-09-02T13:04:05Z"},
+		{name: "time pointer", value: &moment, expected: "2018-09-02T13:04:05Z"},
+		{name: "nil time pointer", value: nilTime, expected: ""},
+		{name: "stringer", value: celsius(21.5), expected: "21.5°C"},
+		{name: "nil stringer", value: nilStringer, expected: ""},
+		{name: "text marshaler", value: net.IPv4(192, 168, 0, 1), expected: "192.168.0.1"},
+		{name: "error", value: errors.New("out of stock"), expected: "out of stock"},
+		{name: "pointer to int", value: func() *int { i := 9; return &i }(), expected: "9"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := tplinator.FormatValue(tc.value)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if actual != tc.expected {
+				t.Errorf("wanted `%v`, got `%v`", tc.expected, actual)
+			}
+		})
+	}
+
+	t.Run(`map`, func(t *testing.T) {
+		_, err := tplinator.FormatValue(map[string]int{"a": 1})
+		if err == nil {
+			t.Error("expecting an error because maps cannot be formatted")
+		}
+	})
+	t.Run(`custom time layout`, func(t *testing.T) {
+		formatter := tplinator.DefaultValueFormatter{TimeLayout: "2006-01-02"}
+		actual, err := formatter.FormatValue(moment)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if actual != "2018-09-02" {
+			t.Errorf("wanted `2018-09-02`, got `%v`", actual)
+		}
+	})
+}
+
+func TestValueFormatterExtensionDependency(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<p data-count="{{go:count}}">{{go:count}} items, in stock: {{go:inStock}}</p>`,
+	))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	actual, err := tpl.RenderString(tplinator.EvaluatorParams{
+		"count":   3,
+		"inStock": true,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<p data-count="3">3 items, in stock: true</p>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+
+	tpl, err = tplinator.Tplinate(strings.NewReader(`<p>{{go:inStock}}</p>`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	tpl.AddExtensionDependencies(&valueFormatterExtDep{
+		formatter: tplinator.ValueFormatterFunc(func(value interface{}) (string, error) {
+			if b, isBool := value.(bool); isBool {
+				if b {
+					return "yes", nil
+				}
+				return "no", nil
+			}
+			return tplinator.FormatValue(value)
+		}),
+	})
+
+	actual, err = tpl.RenderString(tplinator.EvaluatorParams{"inStock": false})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if expected := `<p>no</p>`; actual != expected {
+		t.Errorf("wanted `%v`, got `%v`", expected, actual)
+	}
+}
+
+type valueFormatterExtDep struct {
+	formatter tplinator.ValueFormatter
+}
+
+func (ed *valueFormatterExtDep) Get(depKey tplinator.DependencyKey) interface{} {
+	if depKey == tplinator.ValueFormatterExtDepKey {
+		return ed.formatter
+	}
+	return nil
+}
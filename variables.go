@@ -0,0 +1,375 @@
+package tplinator
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ExpressionExtension is implemented by the extensions that evaluate
+// expressions so that the variables they use can be found without
+// rendering the template.
+type ExpressionExtension interface {
+	Extension
+	Expressions() []string
+}
+
+type VariableScope int
+
+const (
+	// RootVariableScope is for the variables that come from the params
+	// passed to the Template#Render* functions.
+	RootVariableScope VariableScope = iota
+	// RangeItemVariableScope is for the variables that are used inside an
+	// element with a `go-range` attribute. They are looked for on the
+	// range's items first, which shadow the outer scopes.
+	RangeItemVariableScope
+)
+
+func (vs VariableScope) String() string {
+	switch vs {
+	case RootVariableScope:
+		return "root"
+	case RangeItemVariableScope:
+		return "range item"
+	default:
+		return fmt.Sprintf("VariableScope(%d)", int(vs))
+	}
+}
+
+type VariableUsage struct {
+	Expression string
+	Position   Position
+}
+
+type Variable struct {
+	Name  string
+	Scope VariableScope
+	// Range is the expression of the innermost `go-range` whose items
+	// the variable is looked for on. It is empty for RootVariableScope.
+	Range  string
+	Usages []VariableUsage
+}
+
+// Variables returns the root variables that are used by the expressions
+// of the template, sorted by their name, scope and range.
+func (tpl *Template) Variables() ([]Variable, error) {
+	collector := &variableCollector{
+		variables: make(map[variableKey]*Variable),
+		scopes:    []variableCollectorScope{{key: variableKey{scope: RootVariableScope}, isMerged: true}},
+	}
+	for _, rootNode := range tpl.rootNodes {
+		if err := walkExpressions(rootNode, collector); err != nil {
+			return nil, err
+		}
+	}
+
+	variables := make([]Variable, 0, len(collector.variables))
+	for _, variable := range collector.variables {
+		variables = append(variables, *variable)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		if variables[i].Name != variables[j].Name {
+			return variables[i].Name < variables[j].Name
+		}
+		if variables[i].Scope != variables[j].Scope {
+			return variables[i].Scope < variables[j].Scope
+		}
+		return variables[i].Range < variables[j].Range
+	})
+	return variables, nil
+}
+
+type expressionKind int
+
+const (
+	valueExpression expressionKind = iota
+	conditionExpression
+	rangeExpression
+	countExpression
+)
+
+type expressionVisitor interface {
+	visitExpression(node *Node, expression string, kind expressionKind) error
+	// enterRange is called after the expression of a range was visited.
+	// The expressions that are visited until leaveRange is called are
+	// evaluated using the range's items after the modifiers are applied,
+	// which are only some of the range's modifiers for the condition of a
+	// `where` modifier.
+	enterRange(node *Node, rangeExt *RangeExtension, modifiers []pipelineFilterCall)
+	leaveRange()
+}
+
+// walkExpressions visits the expressions of the extensions of the node
+// and its children. The expressions of the extensions that come before a
+// RangeExtension are evaluated using the scope of the node's parent while
+// the rest use the range's items.
+func walkExpressions(node *Node, visitor expressionVisitor) error {
+	enteredRanges := 0
+	defer func() {
+		for ; enteredRanges > 0; enteredRanges-- {
+			visitor.leaveRange()
+		}
+	}()
+
+	hasPlaceholderChildren := false
+	for _, extension := range node.extensions {
+		switch ext := extension.(type) {
+		case *TextExtension:
+			// the children are only a placeholder for the text
+			hasPlaceholderChildren = true
+			if err := visitor.visitExpression(node, ext.expression, valueExpression); err != nil {
+				return err
+			}
+		case *TranslateExtension:
+			// the children are replaced by the message
+			hasPlaceholderChildren = true
+			if ext.paramsExpression != "" {
+				if err := visitor.visitExpression(node, ext.paramsExpression, valueExpression); err != nil {
+					return err
+				}
+			}
+		case *RangeExtension:
+			// the empty branch was removed from the tree but it is rendered
+			// in place of the node using the node's scope
+			if ext.emptyNode != nil {
+				if err := walkExpressions(ext.emptyNode, visitor); err != nil {
+					return err
+				}
+			}
+			kind := rangeExpression
+			if ext.isRepeat {
+				kind = countExpression
+			}
+			if err := visitor.visitExpression(node, ext.sourceVarName, kind); err != nil {
+				return err
+			}
+			for modifierIdx, modifier := range ext.modifiers {
+				if modifier.name == "where" {
+					visitor.enterRange(node, ext, ext.modifiers[:modifierIdx])
+				}
+				for _, arg := range modifier.args {
+					argKind := valueExpression
+					if modifier.name == "where" {
+						argKind = conditionExpression
+					}
+					if err := visitor.visitExpression(node, arg, argKind); err != nil {
+						return err
+					}
+				}
+				if modifier.name == "where" {
+					visitor.leaveRange()
+				}
+			}
+			visitor.enterRange(node, ext, ext.modifiers)
+			enteredRanges++
+		case *ConditionalExtension:
+			// the other branches were removed from the tree but they are
+			// rendered in place of the node
+			for conditionIdx, condition := range ext.conditions {
+				err := visitor.visitExpression(condition.node, condition.conditionalExpression, conditionExpression)
+				if err != nil {
+					return err
+				}
+				if conditionIdx > 0 {
+					if err := walkExpressions(condition.node, visitor); err != nil {
+						return err
+					}
+				}
+			}
+			if ext.elseNode != nil {
+				if err := walkExpressions(ext.elseNode, visitor); err != nil {
+					return err
+				}
+			}
+		case *SwitchCaseExtension:
+			// like the branches of a ConditionalExtension, the other cases
+			// were removed from the tree
+			for caseIdx, c := range ext.cases {
+				for _, value := range c.values {
+					if err := visitor.visitExpression(c.node, value, valueExpression); err != nil {
+						return err
+					}
+				}
+				if caseIdx > 0 {
+					if err := walkExpressions(c.node, visitor); err != nil {
+						return err
+					}
+				}
+			}
+		case *AttributeBindingExtension:
+			for _, binding := range ext.bindings {
+				kind := valueExpression
+				if binding.isBoolean {
+					kind = conditionExpression
+				}
+				if err := visitor.visitExpression(node, binding.expression, kind); err != nil {
+					return err
+				}
+			}
+		case *ConditionalClassExtension:
+			for _, conditionalClass := range ext.conditionalClasses {
+				err := visitor.visitExpression(node, conditionalClass.conditionalExpression, conditionExpression)
+				if err != nil {
+					return err
+				}
+			}
+			if ext.classExpression != "" {
+				if err := visitor.visitExpression(node, ext.classExpression, valueExpression); err != nil {
+					return err
+				}
+			}
+		case ExpressionExtension:
+			for _, expression := range ext.Expressions() {
+				if err := visitor.visitExpression(node, expression, valueExpression); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if hasPlaceholderChildren {
+		return nil
+	}
+	var err error
+	node.Children(func(_ int, child *Node) bool {
+		err = walkExpressions(child, visitor)
+		return err == nil
+	})
+	return err
+}
+
+type variableKey struct {
+	name      string
+	scope     VariableScope
+	rangeExpr string
+}
+
+type variableCollectorScope struct {
+	key variableKey
+	// aliases are the names that a range adds to the scope, like `$loop`
+	// and the names of its items. The other variables belong to the outer
+	// scope unless the range merges its items into the scope.
+	aliases  map[string]bool
+	isMerged bool
+}
+
+type variableCollector struct {
+	variables map[variableKey]*Variable
+	scopes    []variableCollectorScope
+}
+
+func (vc *variableCollector) enterRange(_ *Node, rangeExt *RangeExtension, _ []pipelineFilterCall) {
+	scope := variableCollectorScope{aliases: map[string]bool{LoopParam: true}}
+	switch {
+	case rangeExt.isRepeat:
+		// the items of a repeat only add the `$loop`
+	case rangeExt.alias != "":
+		scope.aliases[rangeExt.alias] = true
+		if rangeExt.secondAlias != "" {
+			scope.aliases[rangeExt.secondAlias] = true
+		}
+	default:
+		scope.key = variableKey{scope: RangeItemVariableScope, rangeExpr: rangeExt.sourceVarName}
+		scope.isMerged = true
+	}
+	vc.scopes = append(vc.scopes, scope)
+}
+
+func (vc *variableCollector) leaveRange() {
+	vc.scopes = vc.scopes[:len(vc.scopes)-1]
+}
+
+func (vc *variableCollector) visitExpression(node *Node, expression string, _ expressionKind) error {
+	names, err := pipelineVariables(expression)
+	if err != nil {
+		return fmt.Errorf("template: %v: %v", node.Position(), err)
+	}
+	for _, name := range names {
+		key, isVariable := vc.variableKey(name)
+		if !isVariable {
+			continue
+		}
+		variable, hasVariable := vc.variables[key]
+		if !hasVariable {
+			variable = &Variable{Name: name, Scope: key.scope, Range: key.rangeExpr}
+			vc.variables[key] = variable
+		}
+		variable.Usages = append(variable.Usages, VariableUsage{
+			Expression: expression,
+			Position:   node.Position(),
+		})
+	}
+	return nil
+}
+
+// variableKey finds the scope of the variable starting from the innermost
+// one. The aliases of the range items are not variables.
+func (vc *variableCollector) variableKey(name string) (variableKey, bool) {
+	for scopeIdx := len(vc.scopes) - 1; scopeIdx >= 0; scopeIdx-- {
+		scope := vc.scopes[scopeIdx]
+		if scope.aliases[name] {
+			return variableKey{}, false
+		} else if scope.isMerged {
+			key := scope.key
+			key.name = name
+			return key, true
+		}
+	}
+	return variableKey{}, false
+}
+
+// pipelineVariables returns the root variables that are used by the
+// expression of the pipeline and by the arguments of its filters.
+func pipelineVariables(input string) ([]string, error) {
+	p, err := parsePipeline(input)
+	if err != nil {
+		return nil, err
+	}
+	expressions := []string{p.expression}
+	for _, filterCall := range p.filters {
+		expressions = append(expressions, filterCall.args...)
+	}
+
+	var names []string
+	for _, expression := range expressions {
+		expressionNames, err := expressionVariables(expression)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, expressionNames...)
+	}
+	return names, nil
+}
+
+// expressionVariables returns the root variables that are used by the
+// expression, e.g. `user` for `user.Profile.DisplayName`. Function names
+// are not variables but the receivers of method calls are.
+func expressionVariables(input string) ([]string, error) {
+	root, err := parseExpression(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	var visit func(node exprNode) bool
+	visit = func(node exprNode) bool {
+		switch node := node.(type) {
+		case *exprIdent:
+			if !seen[node.name] {
+				seen[node.name] = true
+				names = append(names, node.name)
+			}
+		case *exprCall:
+			if _, isFunction := node.callee.(*exprIdent); isFunction {
+				for _, arg := range node.args {
+					walkExprNodes(arg, visit)
+				}
+				return false
+			}
+		}
+		return true
+	}
+	walkExprNodes(root, visit)
+	return names, nil
+}
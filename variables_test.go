@@ -0,0 +1,116 @@
+package tplinator_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/tplinator"
+)
+
+func TestTemplate_Variables(t *testing.T) {
+	tpl, err := tplinator.Tplinate(
+		strings.NewReader("<div>\n"+
+			`<h1 go-if="user.HasRole('admin')">{{go:title | truncate(maxLength)}}</h1>`+"\n"+
+			`<h2 go-elif="isGuest">Guest</h2>`+"\n"+
+			`<h2 go-else><a href="/login?next={{go:path}}">Log in</a></h2>`+"\n"+
+			`<p go-range="items | limit(count)" go-if-class-sold="soldOut">`+
+			`<b go-range="tags">{{go:name}} of {{go:formatMoney(price)}}</b></p>`+"\n"+
+			`</div>`),
+		tplinator.FuncsParserOption(tplinator.FuncMap{
+			"formatMoney": func(cents int) string { return "" },
+		}),
+	)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	variables, err := tpl.Variables()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	root, item := tplinator.RootVariableScope, tplinator.RangeItemVariableScope
+	expected := []tplinator.Variable{
+		{Name: "count", Scope: root, Usages: []tplinator.VariableUsage{
+			{Expression: "items | limit(count)", Position: tplinator.Position{Line: 5, Column: 1}},
+		}},
+		{Name: "isGuest", Scope: root, Usages: []tplinator.VariableUsage{
+			{Expression: "isGuest", Position: tplinator.Position{Line: 3, Column: 1}},
+		}},
+		{Name: "items", Scope: root, Usages: []tplinator.VariableUsage{
+			{Expression: "items | limit(count)", Position: tplinator.Position{Line: 5, Column: 1}},
+		}},
+		{Name: "maxLength", Scope: root, Usages: []tplinator.VariableUsage{
+			{Expression: "title | truncate(maxLength)", Position: tplinator.Position{Line: 2, Column: 35}},
+		}},
+		{Name: "name", Scope: item, Range: "tags", Usages: []tplinator.VariableUsage{
+			{Expression: "name", Position: tplinator.Position{Line: 5, Column: 82}},
+		}},
+		{Name: "path", Scope: root, Usages: []tplinator.VariableUsage{
+			{Expression: "path", Position: tplinator.Position{Line: 4, Column: 13}},
+		}},
+		{Name: "price", Scope: item, Range: "tags", Usages: []tplinator.VariableUsage{
+			{Expression: "formatMoney(price)", Position: tplinator.Position{Line: 5, Column: 82}},
+		}},
+		{Name: "soldOut", Scope: item, Range: "items | limit(count)", Usages: []tplinator.VariableUsage{
+			{Expression: "soldOut", Position: tplinator.Position{Line: 5, Column: 1}},
+		}},
+		{Name: "tags", Scope: item, Range: "items | limit(count)", Usages: []tplinator.VariableUsage{
+			{Expression: "tags", Position: tplinator.Position{Line: 5, Column: 63}},
+		}},
+		{Name: "title", Scope: root, Usages: []tplinator.VariableUsage{
+			{Expression: "title | truncate(maxLength)", Position: tplinator.Position{Line: 2, Column: 35}},
+		}},
+		{Name: "user", Scope: root, Usages: []tplinator.VariableUsage{
+			{Expression: "user.HasRole('admin')", Position: tplinator.Position{Line: 2, Column: 1}},
+		}},
+	}
+	if !reflect.DeepEqual(variables, expected) {
+		t.Errorf("wanted %+v, got %+v", expected, variables)
+	}
+}
+
+func TestTemplate_Variables_InvalidExpression(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(`<p>{{go:'Hi, + name}}</p>`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if _, err := tpl.Variables(); err == nil {
+		t.Error("expecting an error because the expression has an unclosed string literal")
+	}
+}
+
+func TestTemplate_Variables_RangeAlias(t *testing.T) {
+	tpl, err := tplinator.Tplinate(strings.NewReader(
+		`<ul go-range="item, i in items"><li>{{go:i}}. {{go:item.name}} {{go:currency}}</li>` +
+			`<li go-range="tags">{{go:item.name}} {{go:tag}} {{go:$loop.parent.index}}</li></ul>`,
+	))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	variables, err := tpl.Variables()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	var actual []string
+	for _, variable := range variables {
+		actual = append(actual, variable.Name+" ("+variable.Scope.String()+" "+variable.Range+")")
+	}
+	// the items of `tags` are merged into the scope so they might have an
+	// `item` of their own
+	expected := []string{
+		"currency (root )", "item (range item tags)", "items (root )",
+		"tag (range item tags)", "tags (root )",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("wanted %v, got %v", expected, actual)
+	}
+}